@@ -3,7 +3,9 @@ package app
 import (
 	"fmt"
 	"log"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/Joao-lucas-felix/PixelForging/src/backend/server"
 	pixelforging "github.com/Joao-lucas-felix/PixelForging/src/image-processing"
@@ -50,7 +52,7 @@ func GenAPP() *cli.App {
 		// Extract palette command
 		{
 			Name:  "extract-palette",
-			Usage: "Opens the image in the dir that you pass in the flag --input-image=\"[YOUR-IMAGE_PATH}\" and extract the color palette of the image and saves in the path that you pass in the flag --output-image=\"[OUTPUT_IMAGE_PATH]\"\nYou can pass 3 parans to configure the size of palette color image:\n\t--colors-per-row=\"[NUMBER_OF_COLORS_PER_ROW]\"\n\t--width=\"[WIDTH_OF_COLOR_BLOCK]\"\n\t--height=\"[HEIGHT_OF_COLOR_BLOCK]\" \n  --colors-num=\"[NUMBER_OF_COLORS]\"\n\nThe default values are:\n\t--colors-per-row=3\n\t--width=0\n\t--height=0\n\t--colors-num=0",
+			Usage: "Opens the image in the dir that you pass in the flag --input-image=\"[YOUR-IMAGE_PATH}\" and extract the color palette of the image and saves in the path that you pass in the flag --output-image=\"[OUTPUT_IMAGE_PATH]\"\nYou can pass 3 parans to configure the size of palette color image:\n\t--colors-per-row=\"[NUMBER_OF_COLORS_PER_ROW]\"\n\t--width=\"[WIDTH_OF_COLOR_BLOCK]\"\n\t--height=\"[HEIGHT_OF_COLOR_BLOCK]\" \n  --colors-num=\"[NUMBER_OF_COLORS]\"\n  --algorithm=\"[frequency|median-cut|kmeans|octree|wu]\"\n  --seed=\"[KMEANS_RANDOM_SEED]\"\n  --octree-depth=\"[OCTREE_DEPTH_1_TO_8]\"\n  --merge-threshold=\"[DELTA_E_TO_MERGE_SIMILAR_COLORS]\"\n  --delta-e=\"[cie76|cie94|ciede2000]\"\n  --output-format=\"[png|svg|html|gpl|paintnet|jasc|hex|ase|aco]\" (inferred from the --output-image extension: .png, .svg, .html, .gpl, .txt, .pal, .hex, .ase, .aco)\n  --alpha=\"[ignore|premultiply|keep]\"\n  --alpha-threshold=\"[MINIMUM_ALPHA_1_TO_255]\"\n  --histogram-bits=\"[BITS_PER_CHANNEL_1_TO_8]\"\n  --color-shortage=\"[available|pad|error]\" (when the image has fewer colors than --colors-num)\n  --sort=\"[hue|frequency|luminance|hue-ramps|lightness|nearest-neighbor|first-seen]\"\n  --layout=\"[grid|ramps|bar]\" (ramps draws every color ramp, from the shadow to the highlight, in its own row, and bar draws a strip where every color is as wide as its share of the image)\n  --labels=\"[true|false]\" (writes the index, hex code and coverage of every color under its block)\nYou can pass many images separated by commas in --input-image to extract a single palette of all of them\n\nThe default values are:\n\t--colors-per-row=3\n\t--width=0\n\t--height=0\n\t--colors-num=0\n\t--algorithm=frequency\n\t--seed=0 (random)\n\t--octree-depth=8\n\t--merge-threshold=0 (disabled)\n\t--delta-e=ciede2000\n\t--output-format=png\n\t--alpha=ignore\n\t--alpha-threshold=128\n\t--histogram-bits=8\n\t--color-shortage=available\n\t--sort=hue\n\t--layout=grid\n\t--labels=false",
 			Flags: []cli.Flag{
 				cli.StringFlag{
 					Name:  "input-image",
@@ -76,15 +78,64 @@ func GenAPP() *cli.App {
 					Name:  "colors-num",
 					Value: "0",
 				},
+				cli.StringFlag{
+					Name:  "algorithm",
+					Value: pixelforging.AlgorithmFrequency,
+				},
+				cli.StringFlag{
+					Name:  "seed",
+					Value: "0",
+				},
+				cli.StringFlag{
+					Name:  "octree-depth",
+					Value: "0",
+				},
+				cli.StringFlag{
+					Name:  "merge-threshold",
+					Value: "0",
+				},
+				cli.StringFlag{
+					Name:  "delta-e",
+					Value: string(pixelforging.DeltaE2000),
+				},
+				cli.StringFlag{
+					Name:  "output-format",
+					Value: "",
+				},
+				cli.StringFlag{
+					Name:  "alpha",
+					Value: string(pixelforging.AlphaIgnore),
+				},
+				cli.StringFlag{
+					Name:  "alpha-threshold",
+					Value: "128",
+				},
+				cli.StringFlag{
+					Name:  "histogram-bits",
+					Value: "8",
+				},
+				cli.StringFlag{
+					Name:  "color-shortage",
+					Value: string(pixelforging.ShortageAvailable),
+				},
+				cli.StringFlag{
+					Name:  "sort",
+					Value: string(pixelforging.SortHue),
+				},
+				cli.StringFlag{
+					Name:  "layout",
+					Value: string(pixelforging.LayoutGrid),
+				},
+				cli.StringFlag{
+					Name:  "labels",
+					Value: "false",
+				},
 			},
 			Action: func(c *cli.Context) {
 				fmt.Println(logo)
 				inputPath := c.String("input-image")
 				outputPath := c.String("output-image")
-				colorsPerRowS := c.String("colors-per-row")
-				widthS := c.String("width")
-				heightS := c.String("height")
-				colorNumS := c.String("colors-num")
+				outputFormatS := c.String("output-format")
 
 				if inputPath == "" {
 					log.Fatalln("The param --input-image can not be blanck")
@@ -94,33 +145,293 @@ func GenAPP() *cli.App {
 					log.Fatalln("The param --output-image can not be blanck")
 				}
 
-				colorsPerRow, err := strconv.Atoi(colorsPerRowS)
+				options := paletteOptionsFromFlags(c)
+				outputFormat := pixelforging.PaletteFormatFromPath(outputPath)
+				if outputFormatS != "" {
+					var err error
+					if outputFormat, err = pixelforging.ParsePaletteFormat(outputFormatS); err != nil {
+						log.Fatalln("The param --output-format should be one of: png, svg, html, gpl, paintnet, jasc, hex, ase, aco")
+					}
+				}
+				histogram, err := pixelforging.NewHistogram(options.HistogramBits)
+				if err != nil {
+					log.Fatalln(err)
+				}
+
+				fmt.Println("We are forging your palette!")
+
+				// Every image is counted in the same histogram and released before the next one is opened
+				inputPaths := strings.Split(inputPath, ",")
+				for _, path := range inputPaths {
+					image, err := pixelforging.DecodeImage(path)
+					if err != nil {
+						log.Fatalln(err)
+					}
+					if err := histogram.AddImage(image, options.Alpha); err != nil {
+						log.Fatalln(err)
+					}
+				}
+				palette, err := pixelforging.ExtractPaletteFromHistogram(histogram, options)
+				if err != nil {
+					log.Fatalln(err)
+				}
+				for i, entry := range palette.Entries {
+					fmt.Printf("%2d  %-9s  %6.2f%%\n", i+1, entry.Hex, entry.Percentage)
+				}
+				if options.Layout.Mode == pixelforging.LayoutRamps {
+					for _, ramp := range palette.Ramps() {
+						fmt.Printf("%-12s", ramp.Name)
+						for _, entry := range ramp.Entries {
+							fmt.Printf("  %s", entry.Hex)
+						}
+						fmt.Println()
+					}
+				}
+
+				if outputFormat != pixelforging.PaletteFormatPNG {
+					name := strings.TrimSuffix(filepath.Base(inputPaths[0]), filepath.Ext(inputPaths[0]))
+					if outputFormat.IsSwatchSheet() {
+						err = pixelforging.SaveSwatchSheet(palette, outputPath, outputFormat, options.Layout, name)
+					} else {
+						err = pixelforging.SavePalette(palette, outputPath, outputFormat, name)
+					}
+					if err != nil {
+						log.Fatalln(err)
+					}
+					return
+				}
+
+				img, err := pixelforging.RenderPalette(palette, options.Layout)
+				if err != nil {
+					log.Fatalln(err)
+				}
+
+				if err := pixelforging.SaveImage(img, outputPath); err != nil {
+					log.Fatalln(err)
+				}
+			},
+		},
+		// Convert palette command
+		{
+			Name:  "convert-palette",
+			Usage: "Reads the palette file that you pass in the flag --palette=\"[PALETTE_PATH]\" and saves it in the path that you pass in the flag --output-image=\"[OUTPUT_PATH]\"\nThe input format is inferred from the extension: .gpl, .txt (Paint.NET), .pal (JASC), .hex, .ase, .aco or an image of swatches like the ones saved by extract-palette.\nThe output format can be passed in the flag --output-format=\"[png|svg|html|gpl|paintnet|jasc|hex|ase|aco]\" or is inferred from the --output-image extension.\nPNG, SVG and HTML outputs accept the same --colors-per-row, --width, --height, --layout and --labels flags of extract-palette.",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "palette",
+					Value: "",
+				},
+				cli.StringFlag{
+					Name:  "output-image",
+					Value: "",
+				},
+				cli.StringFlag{
+					Name:  "output-format",
+					Value: "",
+				},
+				cli.StringFlag{
+					Name:  "colors-per-row",
+					Value: "0",
+				},
+				cli.StringFlag{
+					Name:  "width",
+					Value: "0",
+				},
+				cli.StringFlag{
+					Name:  "height",
+					Value: "0",
+				},
+				cli.StringFlag{
+					Name:  "layout",
+					Value: string(pixelforging.LayoutGrid),
+				},
+				cli.StringFlag{
+					Name:  "labels",
+					Value: "false",
+				},
+			},
+			Action: func(c *cli.Context) {
+				fmt.Println(logo)
+				palettePath := c.String("palette")
+				outputPath := c.String("output-image")
+				outputFormatS := c.String("output-format")
+
+				if palettePath == "" {
+					log.Fatalln("The param --palette can not be blanck")
+				}
+
+				if outputPath == "" {
+					log.Fatalln("The param --output-image can not be blanck")
+				}
+
+				options := paletteOptionsFromFlags(c)
+				outputFormat := pixelforging.PaletteFormatFromPath(outputPath)
+				if outputFormatS != "" {
+					var err error
+					if outputFormat, err = pixelforging.ParsePaletteFormat(outputFormatS); err != nil {
+						log.Fatalln("The param --output-format should be one of: png, svg, html, gpl, paintnet, jasc, hex, ase, aco")
+					}
+				}
+
+				palette, err := pixelforging.LoadPalette(palettePath)
 				if err != nil {
-					log.Fatalln("The param --colors-per-row should be a int number")
+					log.Fatalln(err)
+				}
+				for i, entry := range palette.Entries {
+					fmt.Printf("%2d  %-9s  %s\n", i+1, entry.Hex, entry.Name)
+				}
+
+				if outputFormat != pixelforging.PaletteFormatPNG {
+					name := strings.TrimSuffix(filepath.Base(palettePath), filepath.Ext(palettePath))
+					if outputFormat.IsSwatchSheet() {
+						err = pixelforging.SaveSwatchSheet(palette, outputPath, outputFormat, options.Layout, name)
+					} else {
+						err = pixelforging.SavePalette(palette, outputPath, outputFormat, name)
+					}
+					if err != nil {
+						log.Fatalln(err)
+					}
+					return
 				}
 
-				width, err := strconv.Atoi(widthS)
+				img, err := pixelforging.RenderPalette(palette, options.Layout)
 				if err != nil {
-					log.Fatalln("The param --width should be a int number")
+					log.Fatalln(err)
+				}
+
+				if err := pixelforging.SaveImage(img, outputPath); err != nil {
+					log.Fatalln(err)
 				}
-				height, err := strconv.Atoi(heightS)
+			},
+		},
+		// Remap image command
+		{
+			Name:  "remap",
+			Usage: "Opens the image in the dir that you pass in the flag --input-image=\"[YOUR-IMAGE_PATH]\", replaces every pixel with the nearest color of a palette and saves the result in the path that you pass in the flag --output-image=\"[OUTPUT_IMAGE_PATH]\"\nThe palette is read from the file passed in the flag --palette=\"[PALETTE_PATH]\" (.gpl, .txt, .pal, .hex, .ase, .aco or an image of swatches). Without it the palette is extracted from the image itself, with the flags:\n\t--colors-num=\"[NUMBER_OF_COLORS]\"\n\t--algorithm=\"[frequency|median-cut|kmeans|octree|wu]\"\n\t--seed=\"[KMEANS_RANDOM_SEED]\"\n  --metric=\"[rgb|weighted-rgb|cie76|cie94|ciede2000]\"\n  --dither=\"[none|floyd-steinberg|jarvis-judice-ninke|stucki|sierra|atkinson|bayer-2x2|bayer-4x4|bayer-8x8|blue-noise|custom]\"\n  --dither-matrix=\"[CUSTOM_THRESHOLD_MAP_LIKE_0,2;3,1]\"\n  --dither-strength=\"[ERROR_DIFFUSED_FROM_0_TO_1]\"\n  --serpentine=\"[true|false]\"\n\nThe default values are:\n\t--colors-num=0\n\t--algorithm=frequency\n\t--seed=0 (random)\n\t--metric=weighted-rgb\n\t--dither=none\n\t--dither-strength=1\n\t--serpentine=true",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "input-image",
+					Value: "",
+				},
+				cli.StringFlag{
+					Name:  "output-image",
+					Value: "",
+				},
+				cli.StringFlag{
+					Name:  "palette",
+					Value: "",
+				},
+				cli.StringFlag{
+					Name:  "colors-num",
+					Value: "0",
+				},
+				cli.StringFlag{
+					Name:  "algorithm",
+					Value: pixelforging.AlgorithmFrequency,
+				},
+				cli.StringFlag{
+					Name:  "seed",
+					Value: "0",
+				},
+				cli.StringFlag{
+					Name:  "metric",
+					Value: string(pixelforging.MetricWeightedRGB),
+				},
+				cli.StringFlag{
+					Name:  "dither",
+					Value: string(pixelforging.DitherNone),
+				},
+				cli.StringFlag{
+					Name:  "dither-matrix",
+					Value: "",
+				},
+				cli.StringFlag{
+					Name:  "dither-strength",
+					Value: "1",
+				},
+				cli.StringFlag{
+					Name:  "serpentine",
+					Value: "true",
+				},
+			},
+			Action: func(c *cli.Context) {
+				fmt.Println(logo)
+				inputPath := c.String("input-image")
+				outputPath := c.String("output-image")
+				palettePath := c.String("palette")
+				metricS := c.String("metric")
+				ditherS := c.String("dither")
+				ditherMatrixS := c.String("dither-matrix")
+				ditherStrengthS := c.String("dither-strength")
+				serpentineS := c.String("serpentine")
+
+				if inputPath == "" {
+					log.Fatalln("The param --input-image can not be blanck")
+				}
+
+				if outputPath == "" {
+					log.Fatalln("The param --output-image can not be blanck")
+				}
+
+				options := paletteOptionsFromFlags(c)
+				metric, err := pixelforging.ParseDistanceMetric(metricS)
+				if err != nil {
+					log.Fatalln("The param --metric should be one of: rgb, weighted-rgb, cie76, cie94, ciede2000")
+				}
+				dither, err := pixelforging.ParseDitherMethod(ditherS)
 				if err != nil {
-					log.Fatalln("The param --height should be a int number")
+					log.Fatalln("The param --dither should be one of: none, floyd-steinberg, jarvis-judice-ninke, stucki, sierra, atkinson, bayer-2x2, bayer-4x4, bayer-8x8, blue-noise, custom")
 				}
-				colorNum, err := strconv.Atoi(colorNumS)
+				var ditherMatrix [][]float64
+				if dither == pixelforging.DitherCustomMatrix {
+					if ditherMatrix, err = pixelforging.ParseDitherMatrix(ditherMatrixS); err != nil {
+						log.Fatalln("The param --dither-matrix should be rows of numbers >= 0 separated by \";\", like \"0,2;3,1\": ", err)
+					}
+				}
+				ditherStrength, err := strconv.ParseFloat(ditherStrengthS, 64)
+				if err != nil || ditherStrength <= 0 || ditherStrength > 1 {
+					log.Fatalln("The param --dither-strength should be a number greater than 0 and up to 1")
+				}
+				serpentine, err := strconv.ParseBool(serpentineS)
 				if err != nil {
-					log.Fatalln("The param --colors-num should be a int number")
+					log.Fatalln("The param --serpentine should be true or false")
 				}
 				image, err := pixelforging.DecodeImage(inputPath)
 				if err != nil {
 					log.Fatalln(err)
 				}
 
-				fmt.Println("We are forging your palette!")
+				var palette pixelforging.Palette
+				if palettePath != "" {
+					if palette, err = pixelforging.LoadPalette(palettePath); err != nil {
+						log.Fatalln(err)
+					}
+				} else {
+					if palette, err = pixelforging.ExtractPalette(image, options); err != nil {
+						log.Fatalln(err)
+					}
+				}
 
-				img := pixelforging.ExtractColorPalette(image, colorsPerRow, width, height, colorNum)
+				fmt.Println("We are forging your image!")
 
-				if err := pixelforging.SaveImage(img, outputPath); err != nil {
+				remapped, err := pixelforging.DitherImage(image, palette, metric, pixelforging.DitherOptions{
+					Method:     dither,
+					Strength:   ditherStrength,
+					RasterScan: !serpentine,
+					Matrix:     ditherMatrix,
+				})
+				if err != nil {
+					log.Fatalln(err)
+				}
+
+				// Palettes that fit in an indexed image are saved as one, keeping the order of the colors
+				if paletted, err := pixelforging.ToPaletted(remapped, palette); err == nil {
+					err = pixelforging.SaveImage(paletted, outputPath)
+					if err != nil {
+						log.Fatalln(err)
+					}
+					return
+				}
+				if err := pixelforging.SaveImage(remapped, outputPath); err != nil {
 					log.Fatalln(err)
 				}
 			},
@@ -0,0 +1,72 @@
+package app
+
+import (
+	"log"
+	"strconv"
+
+	pixelforging "github.com/Joao-lucas-felix/PixelForging/src/image-processing"
+	"github.com/urfave/cli"
+)
+
+// paletteOptionsFromFlags reads the palette options from the flags of the command. Flags
+// that the command does not have, or that are blank, keep the default of the option.
+func paletteOptionsFromFlags(c *cli.Context) pixelforging.PaletteOptions {
+	options := pixelforging.PaletteOptions{
+		Algorithm: c.String("algorithm"),
+		Metric:    pixelforging.DeltaEFormula(c.String("delta-e")),
+		Sort:      pixelforging.SortOrder(c.String("sort")),
+		Alpha:     pixelforging.AlphaOptions{Policy: pixelforging.AlphaPolicy(c.String("alpha"))},
+		Shortage:  pixelforging.ShortagePolicy(c.String("color-shortage")),
+		Layout:    pixelforging.PaletteLayout{Mode: pixelforging.LayoutMode(c.String("layout"))},
+	}
+
+	intFlag := func(name string, value *int) {
+		if s := c.String(name); s != "" {
+			v, err := strconv.Atoi(s)
+			if err != nil {
+				log.Fatalf("The param --%s should be a int number\n", name)
+			}
+			*value = v
+		}
+	}
+	intFlag("colors-num", &options.ColorNum)
+	intFlag("octree-depth", &options.OctreeDepth)
+	intFlag("histogram-bits", &options.HistogramBits)
+	intFlag("colors-per-row", &options.Layout.ColorsPerRow)
+	intFlag("width", &options.Layout.ColorWidth)
+	intFlag("height", &options.Layout.ColorHeight)
+
+	if s := c.String("seed"); s != "" {
+		seed, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			log.Fatalln("The param --seed should be a int number")
+		}
+		options.Seed = seed
+	}
+	if s := c.String("merge-threshold"); s != "" {
+		mergeThreshold, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			log.Fatalln("The param --merge-threshold should be a number")
+		}
+		options.MergeThreshold = mergeThreshold
+	}
+	if s := c.String("labels"); s != "" {
+		labels, err := strconv.ParseBool(s)
+		if err != nil {
+			log.Fatalln("The param --labels should be true or false")
+		}
+		options.Layout.Labels = labels
+	}
+	if s := c.String("alpha-threshold"); s != "" {
+		alphaThreshold, err := strconv.ParseUint(s, 10, 8)
+		if err != nil || alphaThreshold == 0 {
+			log.Fatalln("The param --alpha-threshold should be a int number between 1 and 255")
+		}
+		options.Alpha.Threshold = uint8(alphaThreshold)
+	}
+
+	if err := options.Validate(); err != nil {
+		log.Fatalln("Invalid palette options: ", err)
+	}
+	return options
+}
@@ -21,6 +21,75 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// Order of the colors of the extracted palette
+type SortOrder int32
+
+const (
+	// By hue and then by lightness
+	SortOrder_SORT_HUE SortOrder = 0
+	// By the number of pixels of each color, from the most frequent
+	SortOrder_SORT_FREQUENCY SortOrder = 1
+	// By Rec.709 relative luminance, from the darkest
+	SortOrder_SORT_LUMINANCE SortOrder = 2
+	// Grouped by hue family, the grays first, each family from the darkest to the lightest
+	SortOrder_SORT_HUE_RAMPS SortOrder = 3
+	// By CIELAB lightness, from the darkest
+	SortOrder_SORT_LIGHTNESS SortOrder = 4
+	// A path from the darkest color that always moves to the nearest color, a smooth gradient, for palettes of up to 1024 colors
+	SortOrder_SORT_NEAREST_NEIGHBOR SortOrder = 5
+	// By the first pixel of each color, reading the image row by row
+	SortOrder_SORT_FIRST_SEEN SortOrder = 6
+)
+
+// Enum value maps for SortOrder.
+var (
+	SortOrder_name = map[int32]string{
+		0: "SORT_HUE",
+		1: "SORT_FREQUENCY",
+		2: "SORT_LUMINANCE",
+		3: "SORT_HUE_RAMPS",
+		4: "SORT_LIGHTNESS",
+		5: "SORT_NEAREST_NEIGHBOR",
+		6: "SORT_FIRST_SEEN",
+	}
+	SortOrder_value = map[string]int32{
+		"SORT_HUE":              0,
+		"SORT_FREQUENCY":        1,
+		"SORT_LUMINANCE":        2,
+		"SORT_HUE_RAMPS":        3,
+		"SORT_LIGHTNESS":        4,
+		"SORT_NEAREST_NEIGHBOR": 5,
+		"SORT_FIRST_SEEN":       6,
+	}
+)
+
+func (x SortOrder) Enum() *SortOrder {
+	p := new(SortOrder)
+	*p = x
+	return p
+}
+
+func (x SortOrder) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (SortOrder) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_pixelforging_proto_enumTypes[0].Descriptor()
+}
+
+func (SortOrder) Type() protoreflect.EnumType {
+	return &file_proto_pixelforging_proto_enumTypes[0]
+}
+
+func (x SortOrder) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use SortOrder.Descriptor instead.
+func (SortOrder) EnumDescriptor() ([]byte, []int) {
+	return file_proto_pixelforging_proto_rawDescGZIP(), []int{0}
+}
+
 type WakeMsg struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
@@ -108,10 +177,44 @@ type ExtractPaletteInput struct {
 	FileType  string                 `protobuf:"bytes,3,opt,name=fileType,proto3" json:"fileType,omitempty"`
 	// The following fields are optional and can be set to 0 if not needed
 	// The following fields configure shape of the palette
-	ColorsPerRow  int32 `protobuf:"varint,4,opt,name=colorsPerRow,proto3" json:"colorsPerRow,omitempty"`
-	ColorWidth    int32 `protobuf:"varint,5,opt,name=colorWidth,proto3" json:"colorWidth,omitempty"`
-	ColorHeight   int32 `protobuf:"varint,6,opt,name=colorHeight,proto3" json:"colorHeight,omitempty"`
-	ColorNum      int32 `protobuf:"varint,7,opt,name=colorNum,proto3" json:"colorNum,omitempty"`
+	ColorsPerRow int32 `protobuf:"varint,4,opt,name=colorsPerRow,proto3" json:"colorsPerRow,omitempty"`
+	ColorWidth   int32 `protobuf:"varint,5,opt,name=colorWidth,proto3" json:"colorWidth,omitempty"`
+	ColorHeight  int32 `protobuf:"varint,6,opt,name=colorHeight,proto3" json:"colorHeight,omitempty"`
+	ColorNum     int32 `protobuf:"varint,7,opt,name=colorNum,proto3" json:"colorNum,omitempty"`
+	// Algorithm used to pick the palette colors: "frequency" (default), "median-cut", "kmeans", "octree" or "wu"
+	Algorithm string `protobuf:"bytes,8,opt,name=algorithm,proto3" json:"algorithm,omitempty"`
+	// Seed of the "kmeans" initialization, 0 picks a random seed
+	Seed int64 `protobuf:"varint,9,opt,name=seed,proto3" json:"seed,omitempty"`
+	// Depth of the "octree" tree, from 1 to 8, 0 uses the maximum depth
+	OctreeDepth int32 `protobuf:"varint,10,opt,name=octreeDepth,proto3" json:"octreeDepth,omitempty"`
+	// Delta-E under which the "frequency" algorithm merges similar colors, 0 disables the merge
+	MergeThreshold float64 `protobuf:"fixed64,11,opt,name=mergeThreshold,proto3" json:"mergeThreshold,omitempty"`
+	// Formula used to compare colors: "cie76", "cie94" or "ciede2000" (default)
+	DeltaE string `protobuf:"bytes,12,opt,name=deltaE,proto3" json:"deltaE,omitempty"`
+	// Format of the returned palette: "png" (default, the swatch grid image), a vector swatch sheet:
+	// "svg" or "html" (a page that copies the hex code of the clicked color), or a palette file
+	// format: "gpl", "paintnet", "jasc", "hex", "ase" (Adobe Swatch Exchange) or "aco" (Photoshop)
+	PaletteFormat string `protobuf:"bytes,13,opt,name=paletteFormat,proto3" json:"paletteFormat,omitempty"`
+	// How the transparency of the pixels is handled: "ignore" (default), "premultiply" or "keep",
+	// which is only supported by "median-cut" and "frequency"
+	AlphaPolicy string `protobuf:"bytes,14,opt,name=alphaPolicy,proto3" json:"alphaPolicy,omitempty"`
+	// Minimum alpha, from 0 to 255, of the pixels used by the "ignore" policy. 0 uses 128
+	AlphaThreshold uint32 `protobuf:"varint,15,opt,name=alphaThreshold,proto3" json:"alphaThreshold,omitempty"`
+	// Bits kept of each RGB channel when the colors are counted, from 1 to 8. Fewer bits group
+	// similar colors, like 5 for the 5-5-5 colors of old consoles. 0 keeps the exact colors
+	HistogramBits int32 `protobuf:"varint,16,opt,name=histogramBits,proto3" json:"histogramBits,omitempty"`
+	// What is done when the image has fewer colors than colorNum: "available" (default) returns
+	// the colors the image has, "pad" fills the missing ones with transparent colors and
+	// "error" fails with the FailedPrecondition status
+	ColorShortage string `protobuf:"bytes,17,opt,name=colorShortage,proto3" json:"colorShortage,omitempty"`
+	// Order of the colors of the palette, SORT_HUE by default
+	Sort SortOrder `protobuf:"varint,18,opt,name=sort,proto3,enum=pixelforging_grpc.SortOrder" json:"sort,omitempty"`
+	// Arrangement of the color blocks of the "png" palette: "grid" (default), colorsPerRow blocks
+	// per row, "ramps", one row per color ramp, from the shadow to the highlight, or "bar", a strip
+	// where every color is as wide as its share of the pixels of the image
+	Layout string `protobuf:"bytes,19,opt,name=layout,proto3" json:"layout,omitempty"`
+	// Writes the index, hex code and coverage of every color under its block in the "png" palette
+	Labels        bool `protobuf:"varint,20,opt,name=labels,proto3" json:"labels,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -195,11 +298,108 @@ func (x *ExtractPaletteInput) GetColorNum() int32 {
 	return 0
 }
 
+func (x *ExtractPaletteInput) GetAlgorithm() string {
+	if x != nil {
+		return x.Algorithm
+	}
+	return ""
+}
+
+func (x *ExtractPaletteInput) GetSeed() int64 {
+	if x != nil {
+		return x.Seed
+	}
+	return 0
+}
+
+func (x *ExtractPaletteInput) GetOctreeDepth() int32 {
+	if x != nil {
+		return x.OctreeDepth
+	}
+	return 0
+}
+
+func (x *ExtractPaletteInput) GetMergeThreshold() float64 {
+	if x != nil {
+		return x.MergeThreshold
+	}
+	return 0
+}
+
+func (x *ExtractPaletteInput) GetDeltaE() string {
+	if x != nil {
+		return x.DeltaE
+	}
+	return ""
+}
+
+func (x *ExtractPaletteInput) GetPaletteFormat() string {
+	if x != nil {
+		return x.PaletteFormat
+	}
+	return ""
+}
+
+func (x *ExtractPaletteInput) GetAlphaPolicy() string {
+	if x != nil {
+		return x.AlphaPolicy
+	}
+	return ""
+}
+
+func (x *ExtractPaletteInput) GetAlphaThreshold() uint32 {
+	if x != nil {
+		return x.AlphaThreshold
+	}
+	return 0
+}
+
+func (x *ExtractPaletteInput) GetHistogramBits() int32 {
+	if x != nil {
+		return x.HistogramBits
+	}
+	return 0
+}
+
+func (x *ExtractPaletteInput) GetColorShortage() string {
+	if x != nil {
+		return x.ColorShortage
+	}
+	return ""
+}
+
+func (x *ExtractPaletteInput) GetSort() SortOrder {
+	if x != nil {
+		return x.Sort
+	}
+	return SortOrder_SORT_HUE
+}
+
+func (x *ExtractPaletteInput) GetLayout() string {
+	if x != nil {
+		return x.Layout
+	}
+	return ""
+}
+
+func (x *ExtractPaletteInput) GetLabels() bool {
+	if x != nil {
+		return x.Labels
+	}
+	return false
+}
+
 type ExtractPaletteOutput struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	PaletteBytes  []byte                 `protobuf:"bytes,1,opt,name=paletteBytes,proto3" json:"paletteBytes,omitempty"`
-	FileName      string                 `protobuf:"bytes,2,opt,name=fileName,proto3" json:"fileName,omitempty"`
-	FileType      string                 `protobuf:"bytes,3,opt,name=fileType,proto3" json:"fileType,omitempty"`
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	PaletteBytes []byte                 `protobuf:"bytes,1,opt,name=paletteBytes,proto3" json:"paletteBytes,omitempty"`
+	FileName     string                 `protobuf:"bytes,2,opt,name=fileName,proto3" json:"fileName,omitempty"`
+	FileType     string                 `protobuf:"bytes,3,opt,name=fileType,proto3" json:"fileType,omitempty"`
+	// The colors of the palette, in order. They are only sent in the first message of the stream
+	Colors []*PaletteColor `protobuf:"bytes,4,rep,name=colors,proto3" json:"colors,omitempty"`
+	// Number of non transparent pixels of the image
+	TotalPixels int64 `protobuf:"varint,5,opt,name=totalPixels,proto3" json:"totalPixels,omitempty"`
+	// The colors grouped in hue ramps, the grays first. They are only sent in the first message of the stream
+	Ramps         []*PaletteRamp `protobuf:"bytes,6,rep,name=ramps,proto3" json:"ramps,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -255,6 +455,434 @@ func (x *ExtractPaletteOutput) GetFileType() string {
 	return ""
 }
 
+func (x *ExtractPaletteOutput) GetColors() []*PaletteColor {
+	if x != nil {
+		return x.Colors
+	}
+	return nil
+}
+
+func (x *ExtractPaletteOutput) GetTotalPixels() int64 {
+	if x != nil {
+		return x.TotalPixels
+	}
+	return 0
+}
+
+func (x *ExtractPaletteOutput) GetRamps() []*PaletteRamp {
+	if x != nil {
+		return x.Ramps
+	}
+	return nil
+}
+
+type RemapInput struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	FileBytes []byte                 `protobuf:"bytes,1,opt,name=fileBytes,proto3" json:"fileBytes,omitempty"`
+	FileName  string                 `protobuf:"bytes,2,opt,name=fileName,proto3" json:"fileName,omitempty"`
+	FileType  string                 `protobuf:"bytes,3,opt,name=fileType,proto3" json:"fileType,omitempty"`
+	// Palette file used to recolor the image. When empty, the palette is extracted from the image
+	PaletteBytes []byte `protobuf:"bytes,4,opt,name=paletteBytes,proto3" json:"paletteBytes,omitempty"`
+	// Format of paletteBytes: "png" (default, an image of swatches), "gpl", "paintnet", "jasc", "hex", "ase" or "aco"
+	PaletteFormat string `protobuf:"bytes,5,opt,name=paletteFormat,proto3" json:"paletteFormat,omitempty"`
+	// Distance used to find the nearest palette color: "rgb", "weighted-rgb" (default), "cie76", "cie94" or "ciede2000"
+	Metric string `protobuf:"bytes,6,opt,name=metric,proto3" json:"metric,omitempty"`
+	// The following fields configure the palette extracted when paletteBytes is empty
+	ColorNum  int32  `protobuf:"varint,7,opt,name=colorNum,proto3" json:"colorNum,omitempty"`
+	Algorithm string `protobuf:"bytes,8,opt,name=algorithm,proto3" json:"algorithm,omitempty"`
+	Seed      int64  `protobuf:"varint,9,opt,name=seed,proto3" json:"seed,omitempty"`
+	// Dithering method: "none" (default), "floyd-steinberg", "jarvis-judice-ninke", "stucki", "sierra", "atkinson",
+	// "bayer-2x2", "bayer-4x4", "bayer-8x8", "blue-noise" or "custom"
+	Dither string `protobuf:"bytes,10,opt,name=dither,proto3" json:"dither,omitempty"`
+	// Share of the error diffused to the neighbour pixels, from 0 to 1. 0 uses 1
+	DitherStrength float64 `protobuf:"fixed64,11,opt,name=ditherStrength,proto3" json:"ditherStrength,omitempty"`
+	// Processes every line from left to right instead of the default serpentine order
+	RasterScan bool `protobuf:"varint,12,opt,name=rasterScan,proto3" json:"rasterScan,omitempty"`
+	// Threshold map of the "custom" dithering, rows separated by ";" and values by ",", like "0,2;3,1"
+	DitherMatrix  string `protobuf:"bytes,13,opt,name=ditherMatrix,proto3" json:"ditherMatrix,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemapInput) Reset() {
+	*x = RemapInput{}
+	mi := &file_proto_pixelforging_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemapInput) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemapInput) ProtoMessage() {}
+
+func (x *RemapInput) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_pixelforging_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemapInput.ProtoReflect.Descriptor instead.
+func (*RemapInput) Descriptor() ([]byte, []int) {
+	return file_proto_pixelforging_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *RemapInput) GetFileBytes() []byte {
+	if x != nil {
+		return x.FileBytes
+	}
+	return nil
+}
+
+func (x *RemapInput) GetFileName() string {
+	if x != nil {
+		return x.FileName
+	}
+	return ""
+}
+
+func (x *RemapInput) GetFileType() string {
+	if x != nil {
+		return x.FileType
+	}
+	return ""
+}
+
+func (x *RemapInput) GetPaletteBytes() []byte {
+	if x != nil {
+		return x.PaletteBytes
+	}
+	return nil
+}
+
+func (x *RemapInput) GetPaletteFormat() string {
+	if x != nil {
+		return x.PaletteFormat
+	}
+	return ""
+}
+
+func (x *RemapInput) GetMetric() string {
+	if x != nil {
+		return x.Metric
+	}
+	return ""
+}
+
+func (x *RemapInput) GetColorNum() int32 {
+	if x != nil {
+		return x.ColorNum
+	}
+	return 0
+}
+
+func (x *RemapInput) GetAlgorithm() string {
+	if x != nil {
+		return x.Algorithm
+	}
+	return ""
+}
+
+func (x *RemapInput) GetSeed() int64 {
+	if x != nil {
+		return x.Seed
+	}
+	return 0
+}
+
+func (x *RemapInput) GetDither() string {
+	if x != nil {
+		return x.Dither
+	}
+	return ""
+}
+
+func (x *RemapInput) GetDitherStrength() float64 {
+	if x != nil {
+		return x.DitherStrength
+	}
+	return 0
+}
+
+func (x *RemapInput) GetRasterScan() bool {
+	if x != nil {
+		return x.RasterScan
+	}
+	return false
+}
+
+func (x *RemapInput) GetDitherMatrix() string {
+	if x != nil {
+		return x.DitherMatrix
+	}
+	return ""
+}
+
+type RemapOutput struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ImageBytes    []byte                 `protobuf:"bytes,1,opt,name=imageBytes,proto3" json:"imageBytes,omitempty"`
+	FileName      string                 `protobuf:"bytes,2,opt,name=fileName,proto3" json:"fileName,omitempty"`
+	FileType      string                 `protobuf:"bytes,3,opt,name=fileType,proto3" json:"fileType,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemapOutput) Reset() {
+	*x = RemapOutput{}
+	mi := &file_proto_pixelforging_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemapOutput) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemapOutput) ProtoMessage() {}
+
+func (x *RemapOutput) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_pixelforging_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemapOutput.ProtoReflect.Descriptor instead.
+func (*RemapOutput) Descriptor() ([]byte, []int) {
+	return file_proto_pixelforging_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *RemapOutput) GetImageBytes() []byte {
+	if x != nil {
+		return x.ImageBytes
+	}
+	return nil
+}
+
+func (x *RemapOutput) GetFileName() string {
+	if x != nil {
+		return x.FileName
+	}
+	return ""
+}
+
+func (x *RemapOutput) GetFileType() string {
+	if x != nil {
+		return x.FileType
+	}
+	return ""
+}
+
+type PaletteColor struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	R     uint32                 `protobuf:"varint,1,opt,name=r,proto3" json:"r,omitempty"`
+	G     uint32                 `protobuf:"varint,2,opt,name=g,proto3" json:"g,omitempty"`
+	B     uint32                 `protobuf:"varint,3,opt,name=b,proto3" json:"b,omitempty"`
+	A     uint32                 `protobuf:"varint,4,opt,name=a,proto3" json:"a,omitempty"`
+	// "#rrggbb", or "#rrggbbaa" when the color is not fully opaque
+	Hex  string  `protobuf:"bytes,5,opt,name=hex,proto3" json:"hex,omitempty"`
+	H    float64 `protobuf:"fixed64,6,opt,name=h,proto3" json:"h,omitempty"`
+	S    float64 `protobuf:"fixed64,7,opt,name=s,proto3" json:"s,omitempty"`
+	L    float64 `protobuf:"fixed64,8,opt,name=l,proto3" json:"l,omitempty"`
+	LabL float64 `protobuf:"fixed64,9,opt,name=labL,proto3" json:"labL,omitempty"`
+	LabA float64 `protobuf:"fixed64,10,opt,name=labA,proto3" json:"labA,omitempty"`
+	LabB float64 `protobuf:"fixed64,11,opt,name=labB,proto3" json:"labB,omitempty"`
+	// Number of pixels of the image represented by the color
+	Count int64 `protobuf:"varint,12,opt,name=count,proto3" json:"count,omitempty"`
+	// Share of the non transparent pixels of the image represented by the color, from 0 to 100
+	Percentage    float64 `protobuf:"fixed64,13,opt,name=percentage,proto3" json:"percentage,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PaletteColor) Reset() {
+	*x = PaletteColor{}
+	mi := &file_proto_pixelforging_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PaletteColor) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PaletteColor) ProtoMessage() {}
+
+func (x *PaletteColor) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_pixelforging_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PaletteColor.ProtoReflect.Descriptor instead.
+func (*PaletteColor) Descriptor() ([]byte, []int) {
+	return file_proto_pixelforging_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *PaletteColor) GetR() uint32 {
+	if x != nil {
+		return x.R
+	}
+	return 0
+}
+
+func (x *PaletteColor) GetG() uint32 {
+	if x != nil {
+		return x.G
+	}
+	return 0
+}
+
+func (x *PaletteColor) GetB() uint32 {
+	if x != nil {
+		return x.B
+	}
+	return 0
+}
+
+func (x *PaletteColor) GetA() uint32 {
+	if x != nil {
+		return x.A
+	}
+	return 0
+}
+
+func (x *PaletteColor) GetHex() string {
+	if x != nil {
+		return x.Hex
+	}
+	return ""
+}
+
+func (x *PaletteColor) GetH() float64 {
+	if x != nil {
+		return x.H
+	}
+	return 0
+}
+
+func (x *PaletteColor) GetS() float64 {
+	if x != nil {
+		return x.S
+	}
+	return 0
+}
+
+func (x *PaletteColor) GetL() float64 {
+	if x != nil {
+		return x.L
+	}
+	return 0
+}
+
+func (x *PaletteColor) GetLabL() float64 {
+	if x != nil {
+		return x.LabL
+	}
+	return 0
+}
+
+func (x *PaletteColor) GetLabA() float64 {
+	if x != nil {
+		return x.LabA
+	}
+	return 0
+}
+
+func (x *PaletteColor) GetLabB() float64 {
+	if x != nil {
+		return x.LabB
+	}
+	return 0
+}
+
+func (x *PaletteColor) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *PaletteColor) GetPercentage() float64 {
+	if x != nil {
+		return x.Percentage
+	}
+	return 0
+}
+
+// A group of palette colors of similar hue, from the shadow to the highlight
+type PaletteRamp struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Hue family of the most frequent color of the ramp, like "red" or "gray"
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Positions of the colors of the ramp in the colors of the palette, from the darkest to the lightest
+	Colors        []int32 `protobuf:"varint,2,rep,packed,name=colors,proto3" json:"colors,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PaletteRamp) Reset() {
+	*x = PaletteRamp{}
+	mi := &file_proto_pixelforging_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PaletteRamp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PaletteRamp) ProtoMessage() {}
+
+func (x *PaletteRamp) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_pixelforging_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PaletteRamp.ProtoReflect.Descriptor instead.
+func (*PaletteRamp) Descriptor() ([]byte, []int) {
+	return file_proto_pixelforging_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *PaletteRamp) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *PaletteRamp) GetColors() []int32 {
+	if x != nil {
+		return x.Colors
+	}
+	return nil
+}
+
 var File_proto_pixelforging_proto protoreflect.FileDescriptor
 
 const file_proto_pixelforging_proto_rawDesc = "" +
@@ -262,7 +890,7 @@ const file_proto_pixelforging_proto_rawDesc = "" +
 	"\x18proto/pixelforging.proto\x12\x11pixelforging_grpc\"\t\n" +
 	"\aWakeMsg\"\x17\n" +
 	"\x05UpMsg\x12\x0e\n" +
-	"\x02up\x18\x01 \x01(\tR\x02up\"\xed\x01\n" +
+	"\x02up\x18\x01 \x01(\tR\x02up\"\x9f\x05\n" +
 	"\x13ExtractPaletteInput\x12\x1c\n" +
 	"\tfileBytes\x18\x01 \x01(\fR\tfileBytes\x12\x1a\n" +
 	"\bfileName\x18\x02 \x01(\tR\bfileName\x12\x1a\n" +
@@ -272,14 +900,84 @@ const file_proto_pixelforging_proto_rawDesc = "" +
 	"colorWidth\x18\x05 \x01(\x05R\n" +
 	"colorWidth\x12 \n" +
 	"\vcolorHeight\x18\x06 \x01(\x05R\vcolorHeight\x12\x1a\n" +
-	"\bcolorNum\x18\a \x01(\x05R\bcolorNum\"r\n" +
+	"\bcolorNum\x18\a \x01(\x05R\bcolorNum\x12\x1c\n" +
+	"\talgorithm\x18\b \x01(\tR\talgorithm\x12\x12\n" +
+	"\x04seed\x18\t \x01(\x03R\x04seed\x12 \n" +
+	"\voctreeDepth\x18\n" +
+	" \x01(\x05R\voctreeDepth\x12&\n" +
+	"\x0emergeThreshold\x18\v \x01(\x01R\x0emergeThreshold\x12\x16\n" +
+	"\x06deltaE\x18\f \x01(\tR\x06deltaE\x12$\n" +
+	"\rpaletteFormat\x18\r \x01(\tR\rpaletteFormat\x12 \n" +
+	"\valphaPolicy\x18\x0e \x01(\tR\valphaPolicy\x12&\n" +
+	"\x0ealphaThreshold\x18\x0f \x01(\rR\x0ealphaThreshold\x12$\n" +
+	"\rhistogramBits\x18\x10 \x01(\x05R\rhistogramBits\x12$\n" +
+	"\rcolorShortage\x18\x11 \x01(\tR\rcolorShortage\x120\n" +
+	"\x04sort\x18\x12 \x01(\x0e2\x1c.pixelforging_grpc.SortOrderR\x04sort\x12\x16\n" +
+	"\x06layout\x18\x13 \x01(\tR\x06layout\x12\x16\n" +
+	"\x06labels\x18\x14 \x01(\bR\x06labels\"\x83\x02\n" +
 	"\x14ExtractPaletteOutput\x12\"\n" +
 	"\fpaletteBytes\x18\x01 \x01(\fR\fpaletteBytes\x12\x1a\n" +
 	"\bfileName\x18\x02 \x01(\tR\bfileName\x12\x1a\n" +
-	"\bfileType\x18\x03 \x01(\tR\bfileType2\xb3\x01\n" +
+	"\bfileType\x18\x03 \x01(\tR\bfileType\x127\n" +
+	"\x06colors\x18\x04 \x03(\v2\x1f.pixelforging_grpc.PaletteColorR\x06colors\x12 \n" +
+	"\vtotalPixels\x18\x05 \x01(\x03R\vtotalPixels\x124\n" +
+	"\x05ramps\x18\x06 \x03(\v2\x1e.pixelforging_grpc.PaletteRampR\x05ramps\"\x96\x03\n" +
+	"\n" +
+	"RemapInput\x12\x1c\n" +
+	"\tfileBytes\x18\x01 \x01(\fR\tfileBytes\x12\x1a\n" +
+	"\bfileName\x18\x02 \x01(\tR\bfileName\x12\x1a\n" +
+	"\bfileType\x18\x03 \x01(\tR\bfileType\x12\"\n" +
+	"\fpaletteBytes\x18\x04 \x01(\fR\fpaletteBytes\x12$\n" +
+	"\rpaletteFormat\x18\x05 \x01(\tR\rpaletteFormat\x12\x16\n" +
+	"\x06metric\x18\x06 \x01(\tR\x06metric\x12\x1a\n" +
+	"\bcolorNum\x18\a \x01(\x05R\bcolorNum\x12\x1c\n" +
+	"\talgorithm\x18\b \x01(\tR\talgorithm\x12\x12\n" +
+	"\x04seed\x18\t \x01(\x03R\x04seed\x12\x16\n" +
+	"\x06dither\x18\n" +
+	" \x01(\tR\x06dither\x12&\n" +
+	"\x0editherStrength\x18\v \x01(\x01R\x0editherStrength\x12\x1e\n" +
+	"\n" +
+	"rasterScan\x18\f \x01(\bR\n" +
+	"rasterScan\x12\"\n" +
+	"\fditherMatrix\x18\r \x01(\tR\fditherMatrix\"e\n" +
+	"\vRemapOutput\x12\x1e\n" +
+	"\n" +
+	"imageBytes\x18\x01 \x01(\fR\n" +
+	"imageBytes\x12\x1a\n" +
+	"\bfileName\x18\x02 \x01(\tR\bfileName\x12\x1a\n" +
+	"\bfileType\x18\x03 \x01(\tR\bfileType\"\xf4\x01\n" +
+	"\fPaletteColor\x12\f\n" +
+	"\x01r\x18\x01 \x01(\rR\x01r\x12\f\n" +
+	"\x01g\x18\x02 \x01(\rR\x01g\x12\f\n" +
+	"\x01b\x18\x03 \x01(\rR\x01b\x12\f\n" +
+	"\x01a\x18\x04 \x01(\rR\x01a\x12\x10\n" +
+	"\x03hex\x18\x05 \x01(\tR\x03hex\x12\f\n" +
+	"\x01h\x18\x06 \x01(\x01R\x01h\x12\f\n" +
+	"\x01s\x18\a \x01(\x01R\x01s\x12\f\n" +
+	"\x01l\x18\b \x01(\x01R\x01l\x12\x12\n" +
+	"\x04labL\x18\t \x01(\x01R\x04labL\x12\x12\n" +
+	"\x04labA\x18\n" +
+	" \x01(\x01R\x04labA\x12\x12\n" +
+	"\x04labB\x18\v \x01(\x01R\x04labB\x12\x14\n" +
+	"\x05count\x18\f \x01(\x03R\x05count\x12\x1e\n" +
+	"\n" +
+	"percentage\x18\r \x01(\x01R\n" +
+	"percentage\"9\n" +
+	"\vPaletteRamp\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x16\n" +
+	"\x06colors\x18\x02 \x03(\x05R\x06colors*\x99\x01\n" +
+	"\tSortOrder\x12\f\n" +
+	"\bSORT_HUE\x10\x00\x12\x12\n" +
+	"\x0eSORT_FREQUENCY\x10\x01\x12\x12\n" +
+	"\x0eSORT_LUMINANCE\x10\x02\x12\x12\n" +
+	"\x0eSORT_HUE_RAMPS\x10\x03\x12\x12\n" +
+	"\x0eSORT_LIGHTNESS\x10\x04\x12\x19\n" +
+	"\x15SORT_NEAREST_NEIGHBOR\x10\x05\x12\x13\n" +
+	"\x0fSORT_FIRST_SEEN\x10\x062\xff\x01\n" +
 	"\fPixelForging\x12e\n" +
 	"\x0eExtractPalette\x12&.pixelforging_grpc.ExtractPaletteInput\x1a'.pixelforging_grpc.ExtractPaletteOutput(\x010\x01\x12<\n" +
-	"\x04Wake\x12\x1a.pixelforging_grpc.WakeMsg\x1a\x18.pixelforging_grpc.UpMsgB$Z\"./src/backend/pb/pixelforging-grpcb\x06proto3"
+	"\x04Wake\x12\x1a.pixelforging_grpc.WakeMsg\x1a\x18.pixelforging_grpc.UpMsg\x12J\n" +
+	"\x05Remap\x12\x1d.pixelforging_grpc.RemapInput\x1a\x1e.pixelforging_grpc.RemapOutput(\x010\x01B$Z\"./src/backend/pb/pixelforging-grpcb\x06proto3"
 
 var (
 	file_proto_pixelforging_proto_rawDescOnce sync.Once
@@ -293,23 +991,34 @@ func file_proto_pixelforging_proto_rawDescGZIP() []byte {
 	return file_proto_pixelforging_proto_rawDescData
 }
 
-var file_proto_pixelforging_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_proto_pixelforging_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_proto_pixelforging_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
 var file_proto_pixelforging_proto_goTypes = []any{
-	(*WakeMsg)(nil),              // 0: pixelforging_grpc.WakeMsg
-	(*UpMsg)(nil),                // 1: pixelforging_grpc.UpMsg
-	(*ExtractPaletteInput)(nil),  // 2: pixelforging_grpc.ExtractPaletteInput
-	(*ExtractPaletteOutput)(nil), // 3: pixelforging_grpc.ExtractPaletteOutput
+	(SortOrder)(0),               // 0: pixelforging_grpc.SortOrder
+	(*WakeMsg)(nil),              // 1: pixelforging_grpc.WakeMsg
+	(*UpMsg)(nil),                // 2: pixelforging_grpc.UpMsg
+	(*ExtractPaletteInput)(nil),  // 3: pixelforging_grpc.ExtractPaletteInput
+	(*ExtractPaletteOutput)(nil), // 4: pixelforging_grpc.ExtractPaletteOutput
+	(*RemapInput)(nil),           // 5: pixelforging_grpc.RemapInput
+	(*RemapOutput)(nil),          // 6: pixelforging_grpc.RemapOutput
+	(*PaletteColor)(nil),         // 7: pixelforging_grpc.PaletteColor
+	(*PaletteRamp)(nil),          // 8: pixelforging_grpc.PaletteRamp
 }
 var file_proto_pixelforging_proto_depIdxs = []int32{
-	2, // 0: pixelforging_grpc.PixelForging.ExtractPalette:input_type -> pixelforging_grpc.ExtractPaletteInput
-	0, // 1: pixelforging_grpc.PixelForging.Wake:input_type -> pixelforging_grpc.WakeMsg
-	3, // 2: pixelforging_grpc.PixelForging.ExtractPalette:output_type -> pixelforging_grpc.ExtractPaletteOutput
-	1, // 3: pixelforging_grpc.PixelForging.Wake:output_type -> pixelforging_grpc.UpMsg
-	2, // [2:4] is the sub-list for method output_type
-	0, // [0:2] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	0, // 0: pixelforging_grpc.ExtractPaletteInput.sort:type_name -> pixelforging_grpc.SortOrder
+	7, // 1: pixelforging_grpc.ExtractPaletteOutput.colors:type_name -> pixelforging_grpc.PaletteColor
+	8, // 2: pixelforging_grpc.ExtractPaletteOutput.ramps:type_name -> pixelforging_grpc.PaletteRamp
+	3, // 3: pixelforging_grpc.PixelForging.ExtractPalette:input_type -> pixelforging_grpc.ExtractPaletteInput
+	1, // 4: pixelforging_grpc.PixelForging.Wake:input_type -> pixelforging_grpc.WakeMsg
+	5, // 5: pixelforging_grpc.PixelForging.Remap:input_type -> pixelforging_grpc.RemapInput
+	4, // 6: pixelforging_grpc.PixelForging.ExtractPalette:output_type -> pixelforging_grpc.ExtractPaletteOutput
+	2, // 7: pixelforging_grpc.PixelForging.Wake:output_type -> pixelforging_grpc.UpMsg
+	6, // 8: pixelforging_grpc.PixelForging.Remap:output_type -> pixelforging_grpc.RemapOutput
+	6, // [6:9] is the sub-list for method output_type
+	3, // [3:6] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
 }
 
 func init() { file_proto_pixelforging_proto_init() }
@@ -322,13 +1031,14 @@ func file_proto_pixelforging_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_pixelforging_proto_rawDesc), len(file_proto_pixelforging_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   4,
+			NumEnums:      1,
+			NumMessages:   8,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_proto_pixelforging_proto_goTypes,
 		DependencyIndexes: file_proto_pixelforging_proto_depIdxs,
+		EnumInfos:         file_proto_pixelforging_proto_enumTypes,
 		MessageInfos:      file_proto_pixelforging_proto_msgTypes,
 	}.Build()
 	File_proto_pixelforging_proto = out.File
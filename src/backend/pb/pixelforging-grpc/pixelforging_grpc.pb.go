@@ -21,6 +21,7 @@ const _ = grpc.SupportPackageIsVersion9
 const (
 	PixelForging_ExtractPalette_FullMethodName = "/pixelforging_grpc.PixelForging/ExtractPalette"
 	PixelForging_Wake_FullMethodName           = "/pixelforging_grpc.PixelForging/Wake"
+	PixelForging_Remap_FullMethodName          = "/pixelforging_grpc.PixelForging/Remap"
 )
 
 // PixelForgingClient is the client API for PixelForging service.
@@ -29,6 +30,7 @@ const (
 type PixelForgingClient interface {
 	ExtractPalette(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ExtractPaletteInput, ExtractPaletteOutput], error)
 	Wake(ctx context.Context, in *WakeMsg, opts ...grpc.CallOption) (*UpMsg, error)
+	Remap(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[RemapInput, RemapOutput], error)
 }
 
 type pixelForgingClient struct {
@@ -62,12 +64,26 @@ func (c *pixelForgingClient) Wake(ctx context.Context, in *WakeMsg, opts ...grpc
 	return out, nil
 }
 
+func (c *pixelForgingClient) Remap(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[RemapInput, RemapOutput], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PixelForging_ServiceDesc.Streams[1], PixelForging_Remap_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RemapInput, RemapOutput]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PixelForging_RemapClient = grpc.BidiStreamingClient[RemapInput, RemapOutput]
+
 // PixelForgingServer is the server API for PixelForging service.
 // All implementations must embed UnimplementedPixelForgingServer
 // for forward compatibility.
 type PixelForgingServer interface {
 	ExtractPalette(grpc.BidiStreamingServer[ExtractPaletteInput, ExtractPaletteOutput]) error
 	Wake(context.Context, *WakeMsg) (*UpMsg, error)
+	Remap(grpc.BidiStreamingServer[RemapInput, RemapOutput]) error
 	mustEmbedUnimplementedPixelForgingServer()
 }
 
@@ -84,6 +100,9 @@ func (UnimplementedPixelForgingServer) ExtractPalette(grpc.BidiStreamingServer[E
 func (UnimplementedPixelForgingServer) Wake(context.Context, *WakeMsg) (*UpMsg, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Wake not implemented")
 }
+func (UnimplementedPixelForgingServer) Remap(grpc.BidiStreamingServer[RemapInput, RemapOutput]) error {
+	return status.Errorf(codes.Unimplemented, "method Remap not implemented")
+}
 func (UnimplementedPixelForgingServer) mustEmbedUnimplementedPixelForgingServer() {}
 func (UnimplementedPixelForgingServer) testEmbeddedByValue()                      {}
 
@@ -130,6 +149,13 @@ func _PixelForging_Wake_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _PixelForging_Remap_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(PixelForgingServer).Remap(&grpc.GenericServerStream[RemapInput, RemapOutput]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PixelForging_RemapServer = grpc.BidiStreamingServer[RemapInput, RemapOutput]
+
 // PixelForging_ServiceDesc is the grpc.ServiceDesc for PixelForging service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -149,6 +175,12 @@ var PixelForging_ServiceDesc = grpc.ServiceDesc{
 			ServerStreams: true,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "Remap",
+			Handler:       _PixelForging_Remap_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "proto/pixelforging.proto",
 }
@@ -0,0 +1,32 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	pixelforging "github.com/Joao-lucas-felix/PixelForging/src/image-processing"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// statusError converts an error to a gRPC status, so the client can tell a bad request from
+// a failure of the server. The typed errors of the image processing package have their own
+// codes, errors that already are a status, like the ones of Recv and Send, are returned as
+// they are and any other error uses the fallback code.
+func statusError(err error, fallback codes.Code) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	code := fallback
+	switch {
+	case errors.Is(err, pixelforging.ErrInvalidOption),
+		errors.Is(err, pixelforging.ErrInvalidDimensions),
+		errors.Is(err, pixelforging.ErrUnsupportedFormat):
+		code = codes.InvalidArgument
+	case errors.Is(err, pixelforging.ErrTooFewColors):
+		code = codes.FailedPrecondition
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	}
+	return status.Error(code, err.Error())
+}
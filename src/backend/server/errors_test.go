@@ -0,0 +1,46 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	pixelforging "github.com/Joao-lucas-felix/PixelForging/src/image-processing"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		{"invalid option", fmt.Errorf("%w: bad", pixelforging.ErrInvalidOption), codes.InvalidArgument},
+		{"invalid dimensions", fmt.Errorf("%w: bad", pixelforging.ErrInvalidDimensions), codes.InvalidArgument},
+		{"unsupported format", fmt.Errorf("reading: %w", fmt.Errorf("%w: bad", pixelforging.ErrUnsupportedFormat)), codes.InvalidArgument},
+		{"too few colors", fmt.Errorf("%w: bad", pixelforging.ErrTooFewColors), codes.FailedPrecondition},
+		{"canceled", fmt.Errorf("sending: %w", context.Canceled), codes.Canceled},
+		{"deadline", context.DeadlineExceeded, codes.DeadlineExceeded},
+		{"status", status.Error(codes.Unavailable, "gone"), codes.Unavailable},
+		{"other error", errors.New("disk full"), codes.Internal},
+	}
+	for _, tt := range tests {
+		err := statusError(tt.err, codes.Internal)
+		s, ok := status.FromError(err)
+		if !ok {
+			t.Fatalf("%s: %v is not a status", tt.name, err)
+		}
+		if s.Code() != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, s.Code(), tt.want)
+		}
+		if tt.want != codes.Canceled && tt.want != codes.DeadlineExceeded && s.Message() != status.Convert(tt.err).Message() {
+			t.Errorf("%s: got message %q, want %q", tt.name, s.Message(), tt.err.Error())
+		}
+	}
+
+	if s := status.Convert(statusError(errors.New("bad input"), codes.InvalidArgument)); s.Code() != codes.InvalidArgument {
+		t.Errorf("the fallback code was not used: got %v", s.Code())
+	}
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"fmt"
+
+	pixelforging_grpc "github.com/Joao-lucas-felix/PixelForging/src/backend/pb/pixelforging-grpc"
+	pixelforging "github.com/Joao-lucas-felix/PixelForging/src/image-processing"
+)
+
+// sortOrders maps the sort orders of the requests to the ones of the library.
+var sortOrders = map[pixelforging_grpc.SortOrder]pixelforging.SortOrder{
+	pixelforging_grpc.SortOrder_SORT_HUE:              pixelforging.SortHue,
+	pixelforging_grpc.SortOrder_SORT_FREQUENCY:        pixelforging.SortFrequency,
+	pixelforging_grpc.SortOrder_SORT_LUMINANCE:        pixelforging.SortLuminance,
+	pixelforging_grpc.SortOrder_SORT_HUE_RAMPS:        pixelforging.SortHueRamps,
+	pixelforging_grpc.SortOrder_SORT_LIGHTNESS:        pixelforging.SortLightness,
+	pixelforging_grpc.SortOrder_SORT_NEAREST_NEIGHBOR: pixelforging.SortNearest,
+	pixelforging_grpc.SortOrder_SORT_FIRST_SEEN:       pixelforging.SortFirstSeen,
+}
+
+// paletteOptionsFromInput reads the palette options from the fields of the request, the
+// same options of the extract-palette command flags. Fields left at 0 or empty keep the
+// default of the option.
+func paletteOptionsFromInput(input *pixelforging_grpc.ExtractPaletteInput) (pixelforging.PaletteOptions, error) {
+	if input.GetAlphaThreshold() > 255 {
+		return pixelforging.PaletteOptions{}, fmt.Errorf("%w: the alpha threshold should be between 0 and 255, got %d", pixelforging.ErrInvalidOption, input.GetAlphaThreshold())
+	}
+	sort, ok := sortOrders[input.GetSort()]
+	if !ok {
+		return pixelforging.PaletteOptions{}, fmt.Errorf("%w: unknown sort order %d", pixelforging.ErrInvalidOption, input.GetSort())
+	}
+	options := pixelforging.PaletteOptions{
+		Algorithm:      input.GetAlgorithm(),
+		ColorNum:       int(input.GetColorNum()),
+		Seed:           input.GetSeed(),
+		OctreeDepth:    int(input.GetOctreeDepth()),
+		MergeThreshold: input.GetMergeThreshold(),
+		Metric:         pixelforging.DeltaEFormula(input.GetDeltaE()),
+		Sort:           sort,
+		Alpha: pixelforging.AlphaOptions{
+			Policy:    pixelforging.AlphaPolicy(input.GetAlphaPolicy()),
+			Threshold: uint8(input.GetAlphaThreshold()),
+		},
+		HistogramBits: int(input.GetHistogramBits()),
+		Shortage:      pixelforging.ShortagePolicy(input.GetColorShortage()),
+		Layout: pixelforging.PaletteLayout{
+			Mode:         pixelforging.LayoutMode(input.GetLayout()),
+			ColorsPerRow: int(input.GetColorsPerRow()),
+			ColorWidth:   int(input.GetColorWidth()),
+			ColorHeight:  int(input.GetColorHeight()),
+			Labels:       input.GetLabels(),
+		},
+	}
+	return options, options.Validate()
+}
@@ -1,14 +1,18 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"io"
 	"log"
 	"net"
+	"path/filepath"
+	"strings"
 
 	pixelforging_grpc "github.com/Joao-lucas-felix/PixelForging/src/backend/pb/pixelforging-grpc"
 	pixelforging "github.com/Joao-lucas-felix/PixelForging/src/image-processing"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 )
 
 type Server struct {
@@ -17,8 +21,8 @@ type Server struct {
 
 func (s Server) ExtractPalette(srv pixelforging_grpc.PixelForging_ExtractPaletteServer) error {
 	var pixelArt []byte
-	var fileName, fileType string
-	var colorsPerRow, colorWidth, colorHeight, colorNum int32
+	var fileName, fileType, paletteFormat string
+	var input *pixelforging_grpc.ExtractPaletteInput
 
 	log.Println("Extracting palette...")
 	for {
@@ -29,53 +33,253 @@ func (s Server) ExtractPalette(srv pixelforging_grpc.PixelForging_ExtractPalette
 		}
 		if err != nil {
 			log.Println("Error receiving data: ", err)
-			return err
+			return statusError(err, codes.Internal)
 		}
 
 		pixelArt = append(pixelArt, data.GetFileBytes()...)
 		fileName = data.GetFileName()
 		fileType = data.GetFileType()
+		paletteFormat = data.GetPaletteFormat()
 
-		// Get the color palette parameters
-		colorsPerRow = data.GetColorsPerRow()
-		colorHeight = data.GetColorHeight()
-		colorWidth = data.GetColorWidth()
-		colorNum = data.GetColorNum()
+		// The color palette parameters are read from the last message
+		input = data
 	}
 	log.Println("Received file:\t", fileName)
 	log.Println("File type:\t", fileType)
 	log.Println("File size:\t", len(pixelArt))
 
+	options, err := paletteOptionsFromInput(input)
+	if err != nil {
+		log.Println("Error reading the palette options: ", err)
+		return statusError(err, codes.InvalidArgument)
+	}
 	// Convert bytes to image
 	img, _, err := pixelforging.BytesToImage(pixelArt, fileType)
 	if err != nil {
 		log.Println("Error converting bytes to image: ", err)
-		return err
+		return statusError(err, codes.InvalidArgument)
 	}
 	// Extract palette from image
-	img = pixelforging.ExtractColorPalette(img, int(colorsPerRow), int(colorWidth), int(colorHeight), int(colorNum))
-
-	bytesOutput, err := pixelforging.ImageToBytes(img, fileType)
+	palette, err := pixelforging.ExtractPalette(img, options)
 	if err != nil {
-		log.Println("Error converting image to bytes: ", err)
-		return err
+		log.Println("Error extracting the palette: ", err)
+		return statusError(err, codes.Internal)
+	}
+
+	var bytesOutput []byte
+	if paletteFormat != "" && paletteFormat != string(pixelforging.PaletteFormatPNG) {
+		format, err := pixelforging.ParsePaletteFormat(paletteFormat)
+		if err != nil {
+			log.Println("Error selecting the palette format: ", err)
+			return statusError(err, codes.Internal)
+		}
+		var buf bytes.Buffer
+		name := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+		if format.IsSwatchSheet() {
+			err = pixelforging.EncodeSwatchSheet(&buf, palette, format, options.Layout, name)
+		} else {
+			err = pixelforging.EncodePalette(&buf, palette, format, name)
+		}
+		if err != nil {
+			log.Println("Error encoding the palette file: ", err)
+			return statusError(err, codes.Internal)
+		}
+		bytesOutput = buf.Bytes()
+		fileType = string(format)
+	} else {
+		img, err = pixelforging.RenderPalette(palette, options.Layout)
+		if err != nil {
+			log.Println("Error rendering the palette: ", err)
+			return statusError(err, codes.Internal)
+		}
+
+		bytesOutput, err = pixelforging.ImageToBytes(img, fileType)
+		if err != nil {
+			log.Println("Error converting image to bytes: ", err)
+			return statusError(err, codes.Internal)
+		}
 	}
 	// Implementar a logica de chunks
 	log.Println("Palette extracted successfully")
 	log.Println("Sending data...")
-	for _, bytes := range bytesOutput {
-		err := srv.Send(&pixelforging_grpc.ExtractPaletteOutput{
+	for i, bytes := range bytesOutput {
+		output := &pixelforging_grpc.ExtractPaletteOutput{
 			PaletteBytes: []byte{bytes},
 			FileName:     fileName,
 			FileType:     fileType,
+		}
+		// The palette colors are sent only once, with the first chunk
+		if i == 0 {
+			output.Colors = toPaletteColors(palette)
+			output.TotalPixels = int64(palette.TotalPixels)
+			output.Ramps = toPaletteRamps(palette)
+		}
+		err := srv.Send(output)
+		if err != nil {
+			log.Println("Error sending data: ", err)
+			return statusError(err, codes.Internal)
+		}
+	}
+	return nil
+}
+
+// toPaletteColors converts the palette entries to their gRPC representation
+func toPaletteColors(palette pixelforging.Palette) []*pixelforging_grpc.PaletteColor {
+	colors := make([]*pixelforging_grpc.PaletteColor, len(palette.Entries))
+	for i, e := range palette.Entries {
+		colors[i] = &pixelforging_grpc.PaletteColor{
+			R:          uint32(e.Color.R),
+			G:          uint32(e.Color.G),
+			B:          uint32(e.Color.B),
+			A:          uint32(e.Color.A),
+			Hex:        e.Hex,
+			H:          e.HSL.H,
+			S:          e.HSL.S,
+			L:          e.HSL.L,
+			LabL:       e.Lab.L,
+			LabA:       e.Lab.A,
+			LabB:       e.Lab.B,
+			Count:      int64(e.Count),
+			Percentage: e.Percentage,
+		}
+	}
+	return colors
+}
+
+// toPaletteRamps converts the ramps of the palette to their gRPC representation
+func toPaletteRamps(palette pixelforging.Palette) []*pixelforging_grpc.PaletteRamp {
+	ramps := palette.Ramps()
+	out := make([]*pixelforging_grpc.PaletteRamp, len(ramps))
+	for i, ramp := range ramps {
+		out[i] = &pixelforging_grpc.PaletteRamp{Name: ramp.Name}
+		for _, index := range ramp.Indexes {
+			out[i].Colors = append(out[i].Colors, int32(index))
+		}
+	}
+	return out
+}
+
+// remapChunkSize is the number of image bytes sent in each message of Remap
+const remapChunkSize = 64 * 1024
+
+// Remap recolors the received image with a palette
+// @Description: Replaces every pixel of the image with the nearest color of the palette sent in paletteBytes, or of the palette extracted from the image when paletteBytes is empty
+func (s Server) Remap(srv pixelforging_grpc.PixelForging_RemapServer) error {
+	var pixelArt, paletteBytes []byte
+	var fileName, fileType, paletteFormat, metricName, algorithm, dither, ditherMatrix string
+	var colorNum int32
+	var seed int64
+	var ditherStrength float64
+	var rasterScan bool
+
+	log.Println("Remapping image...")
+	for {
+		data, err := srv.Recv()
+		if err == io.EOF {
+			log.Println("Finished receiving data")
+			break
+		}
+		if err != nil {
+			log.Println("Error receiving data: ", err)
+			return statusError(err, codes.Internal)
+		}
+
+		pixelArt = append(pixelArt, data.GetFileBytes()...)
+		paletteBytes = append(paletteBytes, data.GetPaletteBytes()...)
+		fileName = data.GetFileName()
+		fileType = data.GetFileType()
+		paletteFormat = data.GetPaletteFormat()
+		metricName = data.GetMetric()
+		colorNum = data.GetColorNum()
+		algorithm = data.GetAlgorithm()
+		seed = data.GetSeed()
+		dither = data.GetDither()
+		ditherStrength = data.GetDitherStrength()
+		rasterScan = data.GetRasterScan()
+		ditherMatrix = data.GetDitherMatrix()
+	}
+	log.Println("Received file:\t", fileName)
+	log.Println("File type:\t", fileType)
+	log.Println("File size:\t", len(pixelArt))
+
+	img, _, err := pixelforging.BytesToImage(pixelArt, fileType)
+	if err != nil {
+		log.Println("Error converting bytes to image: ", err)
+		return statusError(err, codes.InvalidArgument)
+	}
+	metric, err := pixelforging.ParseDistanceMetric(metricName)
+	if err != nil {
+		log.Println("Error selecting the distance metric: ", err)
+		return statusError(err, codes.Internal)
+	}
+
+	var palette pixelforging.Palette
+	if len(paletteBytes) > 0 {
+		format := pixelforging.PaletteFormatPNG
+		if paletteFormat != "" {
+			if format, err = pixelforging.ParsePaletteFormat(paletteFormat); err != nil {
+				log.Println("Error selecting the palette format: ", err)
+				return statusError(err, codes.Internal)
+			}
+		}
+		if palette, err = pixelforging.DecodePalette(bytes.NewReader(paletteBytes), format); err != nil {
+			log.Println("Error decoding the palette: ", err)
+			return statusError(err, codes.InvalidArgument)
+		}
+	} else {
+		options := pixelforging.PaletteOptions{Algorithm: algorithm, ColorNum: int(colorNum), Seed: seed}
+		if palette, err = pixelforging.ExtractPalette(img, options); err != nil {
+			log.Println("Error extracting the palette: ", err)
+			return statusError(err, codes.Internal)
+		}
+	}
+
+	options := pixelforging.DitherOptions{
+		Method:     pixelforging.DitherMethod(dither),
+		Strength:   ditherStrength,
+		RasterScan: rasterScan,
+	}
+	if options.Method == pixelforging.DitherCustomMatrix {
+		if options.Matrix, err = pixelforging.ParseDitherMatrix(ditherMatrix); err != nil {
+			log.Println("Error reading the dithering matrix: ", err)
+			return statusError(err, codes.Internal)
+		}
+	}
+	remapped, err := pixelforging.DitherImage(img, palette, metric, options)
+	if err != nil {
+		log.Println("Error remapping the image: ", err)
+		return statusError(err, codes.Internal)
+	}
+	// Palettes that fit in an indexed image are sent as one, keeping the order of the colors
+	var bytesOutput []byte
+	paletted, err := pixelforging.ToPaletted(remapped, palette)
+	if err == nil {
+		bytesOutput, err = pixelforging.ImageToBytes(paletted, fileType)
+	} else {
+		bytesOutput, err = pixelforging.ImageToBytes(remapped, fileType)
+	}
+	if err != nil {
+		log.Println("Error converting image to bytes: ", err)
+		return statusError(err, codes.Internal)
+	}
+
+	log.Println("Image remapped successfully")
+	log.Println("Sending data...")
+	for start := 0; start < len(bytesOutput); start += remapChunkSize {
+		end := min(start+remapChunkSize, len(bytesOutput))
+		err := srv.Send(&pixelforging_grpc.RemapOutput{
+			ImageBytes: bytesOutput[start:end],
+			FileName:   fileName,
+			FileType:   fileType,
 		})
 		if err != nil {
 			log.Println("Error sending data: ", err)
-			return err
+			return statusError(err, codes.Internal)
 		}
 	}
 	return nil
 }
+
 // Wake Verify if the server is up 
 // @Description: Verify if the server is up
 func (s Server) Wake(context.Context, *pixelforging_grpc.WakeMsg) (*pixelforging_grpc.UpMsg, error) {
@@ -0,0 +1,392 @@
+package pixelforging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"unicode/utf16"
+)
+
+// Adobe Swatch Exchange (.ase) block types
+const (
+	aseGroupStart = 0xC001
+	aseGroupEnd   = 0xC002
+	aseColorEntry = 0x0001
+	// aseColorNormal is the color type of a swatch that is neither global nor spot
+	aseColorNormal = 2
+)
+
+// Photoshop Color Swatch (.aco) color spaces
+const (
+	acoRGB  = 0
+	acoHSB  = 1
+	acoCMYK = 2
+	acoLab  = 7
+	acoGray = 8
+)
+
+// maxSwatches bounds the number of swatches read from a file, so a corrupted count
+// does not allocate an absurd amount of memory. It is the most an ACO file can count.
+const maxSwatches = math.MaxUint16
+
+const (
+	// aseMaxBlockLength bounds the length of an ASE block. A swatch block holds a name and
+	// at most 4 color values, so a longer block is a corrupted file.
+	aseMaxBlockLength = 4 << 10
+	// aseBlockHeaderLength is the length of the type and the length of an ASE block, the
+	// smallest block there is.
+	aseBlockHeaderLength = 6
+	// aseMaxFileLength bounds the length of an ASE file, far more than maxSwatches swatches
+	// with their names take.
+	aseMaxFileLength = 8 << 20
+	// acoMaxNameLength bounds the length, in UTF-16 units, of an ACO swatch name.
+	acoMaxNameLength = 1 << 10
+)
+
+// encodeASE writes the palette as an Adobe Swatch Exchange file. Consecutive entries of
+// the same group are written inside a group block. Adobe swatches have no alpha channel.
+func encodeASE(w *bufio.Writer, palette Palette) {
+	var blocks bytes.Buffer
+	blockCount := 0
+	writeBlock := func(blockType uint16, data []byte) {
+		binary.Write(&blocks, binary.BigEndian, blockType)
+		binary.Write(&blocks, binary.BigEndian, uint32(len(data)))
+		blocks.Write(data)
+		blockCount++
+	}
+
+	group := ""
+	for _, e := range palette.Entries {
+		if e.Group != group {
+			if group != "" {
+				writeBlock(aseGroupEnd, nil)
+			}
+			if e.Group != "" {
+				writeBlock(aseGroupStart, aseString(e.Group))
+			}
+			group = e.Group
+		}
+
+		var data bytes.Buffer
+		data.Write(aseString(entryName(e)))
+		data.WriteString("RGB ")
+		for _, v := range []uint8{e.Color.R, e.Color.G, e.Color.B} {
+			binary.Write(&data, binary.BigEndian, float32(v)/255)
+		}
+		binary.Write(&data, binary.BigEndian, uint16(aseColorNormal))
+		writeBlock(aseColorEntry, data.Bytes())
+	}
+	if group != "" {
+		writeBlock(aseGroupEnd, nil)
+	}
+
+	w.WriteString("ASEF")
+	binary.Write(w, binary.BigEndian, [2]uint16{1, 0})
+	binary.Write(w, binary.BigEndian, uint32(blockCount))
+	w.Write(blocks.Bytes())
+}
+
+// aseString encodes a name as an ASE string: its length in UTF-16 units (counting the
+// null terminator), followed by the UTF-16BE units and the terminator.
+func aseString(s string) []byte {
+	units := append(utf16.Encode([]rune(s)), 0)
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, uint16(len(units)))
+	binary.Write(&buf, binary.BigEndian, units)
+	return buf.Bytes()
+}
+
+// DecodeASE reads an Adobe Swatch Exchange file. Swatches inside a group keep the
+// group name in PaletteEntry.Group. RGB, CMYK, LAB and Gray swatches are supported.
+func DecodeASE(r io.Reader) (Palette, error) {
+	var header struct {
+		Signature  [4]byte
+		Version    [2]uint16
+		BlockCount uint32
+	}
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return Palette{}, fmt.Errorf("reading ASE header: %w", err)
+	}
+	if string(header.Signature[:]) != "ASEF" {
+		return Palette{}, fmt.Errorf("%w: not an Adobe Swatch Exchange file", ErrUnsupportedFormat)
+	}
+	if header.BlockCount > maxSwatches {
+		return Palette{}, fmt.Errorf("%w: ASE file has too many blocks (%d)", ErrUnsupportedFormat, header.BlockCount)
+	}
+	// The blocks are read from memory, so a block count larger than the blocks the file
+	// can hold is rejected before any of them is read
+	blocks, err := io.ReadAll(io.LimitReader(r, aseMaxFileLength+1))
+	if err != nil {
+		return Palette{}, fmt.Errorf("reading ASE blocks: %w", err)
+	}
+	if len(blocks) > aseMaxFileLength {
+		return Palette{}, fmt.Errorf("%w: ASE file is larger than %d bytes", ErrUnsupportedFormat, aseMaxFileLength)
+	}
+	if int(header.BlockCount)*aseBlockHeaderLength > len(blocks) {
+		return Palette{}, fmt.Errorf("%w: ASE file has %d blocks but only %d bytes", ErrUnsupportedFormat, header.BlockCount, len(blocks))
+	}
+	r = bytes.NewReader(blocks)
+
+	palette := Palette{}
+	group := ""
+	for i := uint32(0); i < header.BlockCount; i++ {
+		var blockType uint16
+		var length uint32
+		if err := binary.Read(r, binary.BigEndian, &blockType); err != nil {
+			return Palette{}, fmt.Errorf("reading ASE block: %w", err)
+		}
+		if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+			return Palette{}, fmt.Errorf("reading ASE block: %w", err)
+		}
+		if length > aseMaxBlockLength {
+			return Palette{}, fmt.Errorf("%w: ASE block is too long (%d bytes)", ErrUnsupportedFormat, length)
+		}
+		data := make([]byte, length)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return Palette{}, fmt.Errorf("reading ASE block: %w", err)
+		}
+
+		switch blockType {
+		case aseGroupStart:
+			name, _, err := readASEString(data)
+			if err != nil {
+				return Palette{}, err
+			}
+			group = name
+		case aseGroupEnd:
+			group = ""
+		case aseColorEntry:
+			entry, err := decodeASEColor(data)
+			if err != nil {
+				return Palette{}, err
+			}
+			entry.Group = group
+			palette.Entries = append(palette.Entries, entry)
+		}
+	}
+	return palette, nil
+}
+
+func readASEString(data []byte) (string, []byte, error) {
+	if len(data) < 2 {
+		return "", nil, errors.New("ASE name is truncated")
+	}
+	n := int(binary.BigEndian.Uint16(data))
+	data = data[2:]
+	if len(data) < n*2 {
+		return "", nil, errors.New("ASE name is truncated")
+	}
+	units := make([]uint16, n)
+	for i := range units {
+		units[i] = binary.BigEndian.Uint16(data[i*2:])
+	}
+	// Drop the null terminator
+	for len(units) > 0 && units[len(units)-1] == 0 {
+		units = units[:len(units)-1]
+	}
+	return string(utf16.Decode(units)), data[n*2:], nil
+}
+
+func decodeASEColor(data []byte) (PaletteEntry, error) {
+	name, data, err := readASEString(data)
+	if err != nil {
+		return PaletteEntry{}, err
+	}
+	if len(data) < 4 {
+		return PaletteEntry{}, errors.New("ASE color is truncated")
+	}
+	model := string(data[:4])
+	data = data[4:]
+
+	values := func(n int) ([]float64, error) {
+		if len(data) < n*4 {
+			return nil, fmt.Errorf("ASE %q color is truncated", model)
+		}
+		v := make([]float64, n)
+		for i := range v {
+			v[i] = float64(math.Float32frombits(binary.BigEndian.Uint32(data[i*4:])))
+		}
+		return v, nil
+	}
+
+	var c color.RGBA
+	switch model {
+	case "RGB ":
+		v, err := values(3)
+		if err != nil {
+			return PaletteEntry{}, err
+		}
+		c = color.RGBA{R: to8Bit(v[0]), G: to8Bit(v[1]), B: to8Bit(v[2]), A: 255}
+	case "CMYK":
+		v, err := values(4)
+		if err != nil {
+			return PaletteEntry{}, err
+		}
+		c = cmykToRGBA(v[0], v[1], v[2], v[3])
+	case "LAB ":
+		v, err := values(3)
+		if err != nil {
+			return PaletteEntry{}, err
+		}
+		c = LabToRGBA(LabColor{L: v[0] * 100, A: v[1], B: v[2]}, 255)
+	case "Gray":
+		v, err := values(1)
+		if err != nil {
+			return PaletteEntry{}, err
+		}
+		g := to8Bit(v[0])
+		c = color.RGBA{R: g, G: g, B: g, A: 255}
+	default:
+		return PaletteEntry{}, fmt.Errorf("%w: ASE color model %q", ErrUnsupportedFormat, model)
+	}
+
+	entry := NewPaletteEntry(c, 0, 0)
+	entry.Name = name
+	return entry, nil
+}
+
+// encodeACO writes the palette as a Photoshop Color Swatch file: a version 1 section
+// with the colors, followed by a version 2 section that repeats them with their names.
+func encodeACO(w *bufio.Writer, palette Palette) {
+	writeColor := func(c color.RGBA) {
+		binary.Write(w, binary.BigEndian, [5]uint16{acoRGB, uint16(c.R) * 0x101, uint16(c.G) * 0x101, uint16(c.B) * 0x101, 0})
+	}
+
+	binary.Write(w, binary.BigEndian, [2]uint16{1, uint16(len(palette.Entries))})
+	for _, e := range palette.Entries {
+		writeColor(e.Color)
+	}
+
+	binary.Write(w, binary.BigEndian, [2]uint16{2, uint16(len(palette.Entries))})
+	for _, e := range palette.Entries {
+		writeColor(e.Color)
+		units := append(utf16.Encode([]rune(entryName(e))), 0)
+		binary.Write(w, binary.BigEndian, uint32(len(units)))
+		binary.Write(w, binary.BigEndian, units)
+	}
+}
+
+// DecodeACO reads a Photoshop Color Swatch file. The names of the version 2 section
+// are used when present. RGB, HSB, CMYK, Lab and grayscale swatches are supported.
+func DecodeACO(r io.Reader) (Palette, error) {
+	palette, err := decodeACOSection(r, 1)
+	if err != nil {
+		return Palette{}, err
+	}
+
+	named, err := decodeACOSection(r, 2)
+	if errors.Is(err, io.EOF) {
+		// Version 1 only file
+		return palette, nil
+	}
+	if err != nil {
+		return Palette{}, err
+	}
+	return named, nil
+}
+
+func decodeACOSection(r io.Reader, version uint16) (Palette, error) {
+	var header [2]uint16
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		if errors.Is(err, io.EOF) {
+			return Palette{}, err
+		}
+		return Palette{}, fmt.Errorf("reading ACO header: %w", err)
+	}
+	if header[0] != version {
+		return Palette{}, fmt.Errorf("expected ACO version %d section, got %d", version, header[0])
+	}
+
+	palette := Palette{}
+	for i := 0; i < int(header[1]); i++ {
+		var values [5]uint16
+		if err := binary.Read(r, binary.BigEndian, &values); err != nil {
+			return Palette{}, fmt.Errorf("reading ACO color: %w", err)
+		}
+		c, err := acoToRGBA(values)
+		if err != nil {
+			return Palette{}, err
+		}
+		entry := NewPaletteEntry(c, 0, 0)
+
+		if version == 2 {
+			var length uint32
+			if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+				return Palette{}, fmt.Errorf("reading ACO name: %w", err)
+			}
+			if length > acoMaxNameLength {
+				return Palette{}, fmt.Errorf("%w: ACO name is too long (%d)", ErrUnsupportedFormat, length)
+			}
+			units := make([]uint16, length)
+			if err := binary.Read(r, binary.BigEndian, units); err != nil {
+				return Palette{}, fmt.Errorf("reading ACO name: %w", err)
+			}
+			for len(units) > 0 && units[len(units)-1] == 0 {
+				units = units[:len(units)-1]
+			}
+			entry.Name = string(utf16.Decode(units))
+		}
+		palette.Entries = append(palette.Entries, entry)
+	}
+	return palette, nil
+}
+
+func acoToRGBA(values [5]uint16) (color.RGBA, error) {
+	switch values[0] {
+	case acoRGB:
+		return color.RGBA{R: uint8(values[1] >> 8), G: uint8(values[2] >> 8), B: uint8(values[3] >> 8), A: 255}, nil
+	case acoHSB:
+		return hsbToRGBA(float64(values[1])/65535*360, float64(values[2])/65535, float64(values[3])/65535), nil
+	case acoCMYK:
+		// 0 means 100% of the ink
+		ink := func(v uint16) float64 { return 1 - float64(v)/65535 }
+		return cmykToRGBA(ink(values[1]), ink(values[2]), ink(values[3]), ink(values[4])), nil
+	case acoLab:
+		lab := LabColor{L: float64(values[1]) / 100, A: float64(int16(values[2])) / 100, B: float64(int16(values[3])) / 100}
+		return LabToRGBA(lab, 255), nil
+	case acoGray:
+		// The value is the ink coverage, 0 is white
+		g := to8Bit(1 - float64(values[1])/10000)
+		return color.RGBA{R: g, G: g, B: g, A: 255}, nil
+	default:
+		return color.RGBA{}, fmt.Errorf("%w: ACO color space %d", ErrUnsupportedFormat, values[0])
+	}
+}
+
+// cmykToRGBA converts CMYK inks, from 0 to 1, without a color profile.
+func cmykToRGBA(c, m, y, k float64) color.RGBA {
+	return color.RGBA{
+		R: to8Bit((1 - c) * (1 - k)),
+		G: to8Bit((1 - m) * (1 - k)),
+		B: to8Bit((1 - y) * (1 - k)),
+		A: 255,
+	}
+}
+
+// hsbToRGBA converts a hue in degrees and a saturation and brightness from 0 to 1.
+func hsbToRGBA(h, s, v float64) color.RGBA {
+	chroma := v * s
+	x := chroma * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g = chroma, x
+	case h < 120:
+		r, g = x, chroma
+	case h < 180:
+		g, b = chroma, x
+	case h < 240:
+		g, b = x, chroma
+	case h < 300:
+		r, b = x, chroma
+	default:
+		r, b = chroma, x
+	}
+	m := v - chroma
+	return color.RGBA{R: to8Bit(r + m), G: to8Bit(g + m), B: to8Bit(b + m), A: 255}
+}
@@ -0,0 +1,137 @@
+package pixelforging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image/color"
+	"testing"
+)
+
+// aseColorBlock returns the data of an ASE color block of the model with the values.
+func aseColorBlock(name, model string, values ...float32) []byte {
+	var data bytes.Buffer
+	data.Write(aseString(name))
+	data.WriteString(model)
+	binary.Write(&data, binary.BigEndian, values)
+	binary.Write(&data, binary.BigEndian, uint16(aseColorNormal))
+	return data.Bytes()
+}
+
+// aseFile returns an ASE file with color blocks of the data.
+func aseFile(blocks ...[]byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("ASEF")
+	binary.Write(&buf, binary.BigEndian, [2]uint16{1, 0})
+	binary.Write(&buf, binary.BigEndian, uint32(len(blocks)))
+	for _, data := range blocks {
+		binary.Write(&buf, binary.BigEndian, uint16(aseColorEntry))
+		binary.Write(&buf, binary.BigEndian, uint32(len(data)))
+		buf.Write(data)
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeASEColorModels(t *testing.T) {
+	file := aseFile(
+		aseColorBlock("rgb", "RGB ", 1, 0.5, 0),
+		aseColorBlock("cmyk", "CMYK", 0, 1, 1, 0.5),
+		aseColorBlock("lab", "LAB ", 1, 0, 0),
+		aseColorBlock("gray", "Gray", 0.2),
+	)
+	palette, err := DecodeASE(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PaletteEntry{
+		{Name: "rgb", Color: color.RGBA{R: 255, G: 128, A: 255}},
+		{Name: "cmyk", Color: color.RGBA{R: 128, A: 255}},
+		{Name: "lab", Color: color.RGBA{R: 255, G: 255, B: 255, A: 255}},
+		{Name: "gray", Color: color.RGBA{R: 51, G: 51, B: 51, A: 255}},
+	}
+	if len(palette.Entries) != len(want) {
+		t.Fatalf("got %d colors, want %d", len(palette.Entries), len(want))
+	}
+	for i, e := range palette.Entries {
+		if e.Name != want[i].Name || e.Color != want[i].Color {
+			t.Errorf("color %d is %q %v, want %q %v", i, e.Name, e.Color, want[i].Name, want[i].Color)
+		}
+	}
+}
+
+func TestASEGroups(t *testing.T) {
+	palette := testPalette()
+	palette.Entries[1].Group = "Skin"
+	palette.Entries[2].Group = "Skin"
+	palette.Entries[4].Group = "Sky"
+	var buf bytes.Buffer
+	if err := EncodePalette(&buf, palette, PaletteFormatASE, "Test"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := DecodeASE(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Entries) != len(palette.Entries) {
+		t.Fatalf("got %d colors, want %d", len(got.Entries), len(palette.Entries))
+	}
+	for i, e := range got.Entries {
+		if e.Group != palette.Entries[i].Group {
+			t.Errorf("color %d is in the group %q, want %q", i, e.Group, palette.Entries[i].Group)
+		}
+	}
+}
+
+func TestDecodeASEErrors(t *testing.T) {
+	tooManyBlocks := aseFile(aseColorBlock("rgb", "RGB ", 1, 1, 1))
+	binary.BigEndian.PutUint32(tooManyBlocks[8:], 1000)
+	tests := []struct {
+		name string
+		file []byte
+	}{
+		{"signature", []byte("ASEX\x00\x01\x00\x00\x00\x00\x00\x00")},
+		{"unknown model", aseFile(aseColorBlock("hsv", "HSV ", 1, 1, 1))},
+		{"huge block", hugeASEBlock()},
+		{"more blocks than the file holds", tooManyBlocks},
+	}
+	for _, tt := range tests {
+		if _, err := DecodeASE(bytes.NewReader(tt.file)); !errors.Is(err, ErrUnsupportedFormat) {
+			t.Errorf("%s: got %v, want ErrUnsupportedFormat", tt.name, err)
+		}
+	}
+}
+
+func TestDecodeACOColorSpaces(t *testing.T) {
+	var buf bytes.Buffer
+	swatches := [][5]uint16{
+		{acoRGB, 0xffff, 0x8000, 0, 0},
+		// Hue 240 degrees, full saturation and brightness
+		{acoHSB, 0xaaaa, 0xffff, 0xffff, 0},
+		// The inks are stored inverted, 0 is 100% of the ink
+		{acoCMYK, 0xffff, 0, 0, 0xffff},
+		{acoLab, 10000, 0, 0, 0},
+		{acoGray, 8000, 0, 0, 0},
+	}
+	// A version 1 file, without names
+	binary.Write(&buf, binary.BigEndian, [2]uint16{1, uint16(len(swatches))})
+	binary.Write(&buf, binary.BigEndian, swatches)
+	palette, err := DecodeACO(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []color.RGBA{
+		{R: 255, G: 128, A: 255},
+		{B: 255, A: 255},
+		{R: 255, A: 255},
+		{R: 255, G: 255, B: 255, A: 255},
+		{R: 51, G: 51, B: 51, A: 255},
+	}
+	if len(palette.Entries) != len(want) {
+		t.Fatalf("got %d colors, want %d", len(palette.Entries), len(want))
+	}
+	for i, e := range palette.Entries {
+		if e.Color != want[i] {
+			t.Errorf("color %d is %v, want %v", i, e.Color, want[i])
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package pixelforging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// AlphaPolicy selects how the transparency of the pixels is handled when a palette is extracted.
+type AlphaPolicy string
+
+// Supported alpha policies. Fully transparent pixels are never part of the palette.
+const (
+	// AlphaIgnore ignores the pixels less opaque than the threshold and counts the others
+	// with their own color, as if they were opaque.
+	AlphaIgnore AlphaPolicy = "ignore"
+	// AlphaPremultiply blends the pixels over black by their alpha and counts the result as
+	// an opaque color, so a half transparent red counts as a dark red.
+	AlphaPremultiply AlphaPolicy = "premultiply"
+	// AlphaKeep keeps the alpha as a dimension of the palette: the same color with different
+	// alphas gives different palette colors. Only the quantizers that tell colors apart by
+	// their alpha support it: median-cut and frequency.
+	AlphaKeep AlphaPolicy = "keep"
+)
+
+// keepsAlpha reports whether the quantizer tells apart colors that only differ in alpha, as
+// AlphaKeep needs. Wu and octree cut the RGB cube and k-means measures distances in Lab, so
+// they merge those colors. The merge of similar colors of the frequency quantizer never
+// merges colors of different alpha. The quantizers of the callers are trusted to support it.
+func keepsAlpha(quantizer Quantizer) bool {
+	switch quantizer.(type) {
+	case WuQuantizer, *WuQuantizer, OctreeQuantizer, *OctreeQuantizer, KMeansQuantizer, *KMeansQuantizer:
+		return false
+	default:
+		return true
+	}
+}
+
+// alphaThresholdDefault is the default alpha threshold of AlphaIgnore: half opaque.
+const alphaThresholdDefault = 128
+
+// AlphaOptions configures how the transparency of the pixels is handled.
+type AlphaOptions struct {
+	// Policy is the alpha policy, empty uses AlphaIgnore.
+	Policy AlphaPolicy
+	// Threshold is the minimum alpha of the pixels used by AlphaIgnore, 0 uses 128.
+	Threshold uint8
+}
+
+// ParseAlphaPolicy validates an alpha policy name. An empty name selects AlphaIgnore.
+func ParseAlphaPolicy(name string) (AlphaPolicy, error) {
+	switch policy := AlphaPolicy(name); policy {
+	case "":
+		return AlphaIgnore, nil
+	case AlphaIgnore, AlphaPremultiply, AlphaKeep:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("%w: unknown alpha policy %q", ErrInvalidOption, name)
+	}
+}
+
+// alphaImage shows the pixels of an image after applying an alpha policy. The ignored
+// pixels are fully transparent, so every extractor skips them.
+type alphaImage struct {
+	image.Image
+	policy    AlphaPolicy
+	threshold uint8
+}
+
+// applyAlphaPolicy returns the image seen through the alpha options.
+func applyAlphaPolicy(img image.Image, options AlphaOptions) (image.Image, error) {
+	policy, err := ParseAlphaPolicy(string(options.Policy))
+	if err != nil {
+		return nil, err
+	}
+	if policy == AlphaKeep {
+		return img, nil
+	}
+	threshold := options.Threshold
+	if threshold == 0 {
+		threshold = alphaThresholdDefault
+	}
+	return alphaImage{Image: img, policy: policy, threshold: threshold}, nil
+}
+
+func (m alphaImage) ColorModel() color.Model {
+	return color.RGBAModel
+}
+
+func (m alphaImage) At(x, y int) color.Color {
+	return m.apply(rgbaAt(m.Image, x, y))
+}
+
+// apply returns the color counted for a pixel of the image.
+func (m alphaImage) apply(c color.RGBA) color.RGBA {
+	if c.A == 0 {
+		return color.RGBA{}
+	}
+	if m.policy == AlphaPremultiply {
+		premultiply := func(v uint8) uint8 {
+			return uint8((uint32(v)*uint32(c.A) + 127) / 255)
+		}
+		return color.RGBA{R: premultiply(c.R), G: premultiply(c.G), B: premultiply(c.B), A: 255}
+	}
+
+	if c.A < m.threshold {
+		return color.RGBA{}
+	}
+	c.A = 255
+	return c
+}
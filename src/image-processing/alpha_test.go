@@ -0,0 +1,71 @@
+package pixelforging
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestValidateAlphaKeep(t *testing.T) {
+	tests := []struct {
+		algorithm      string
+		mergeThreshold float64
+		wantErr        bool
+	}{
+		{AlgorithmFrequency, 0, false},
+		{AlgorithmFrequency, 5, false},
+		{AlgorithmMedianCut, 0, false},
+		{AlgorithmKMeans, 0, true},
+		{AlgorithmOctree, 0, true},
+		{AlgorithmWu, 0, true},
+	}
+	for _, tt := range tests {
+		options := PaletteOptions{Algorithm: tt.algorithm, MergeThreshold: tt.mergeThreshold, Alpha: AlphaOptions{Policy: AlphaKeep}}
+		err := options.Validate()
+		if tt.wantErr && !errors.Is(err, ErrInvalidOption) {
+			t.Errorf("%s, merge threshold %v: got %v, want ErrInvalidOption", tt.algorithm, tt.mergeThreshold, err)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("%s, merge threshold %v: %v", tt.algorithm, tt.mergeThreshold, err)
+		}
+	}
+}
+
+func TestAlphaPolicies(t *testing.T) {
+	// Two rows of opaque red and one of the same red, half transparent
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 3))
+	for x := range 4 {
+		img.SetNRGBA(x, 0, color.NRGBA{R: 200, A: 255})
+		img.SetNRGBA(x, 1, color.NRGBA{R: 200, A: 255})
+		img.SetNRGBA(x, 2, color.NRGBA{R: 200, A: 100})
+	}
+	tests := []struct {
+		alpha          AlphaOptions
+		mergeThreshold float64
+		want           []color.RGBA
+	}{
+		{AlphaOptions{}, 0, []color.RGBA{{R: 200, A: 255}}},
+		{AlphaOptions{Threshold: 50}, 0, []color.RGBA{{R: 200, A: 255}}},
+		{AlphaOptions{Policy: AlphaPremultiply}, 0, []color.RGBA{{R: 200, A: 255}, {R: 78, A: 255}}},
+		{AlphaOptions{Policy: AlphaKeep}, 0, []color.RGBA{{R: 200, A: 255}, {R: 200, A: 100}}},
+		// The merge would join the two reds if it did not tell their alphas apart
+		{AlphaOptions{Policy: AlphaKeep}, 50, []color.RGBA{{R: 200, A: 255}, {R: 200, A: 100}}},
+	}
+	for _, tt := range tests {
+		options := PaletteOptions{ColorNum: 2, MergeThreshold: tt.mergeThreshold, Sort: SortFrequency, Alpha: tt.alpha}
+		palette, err := ExtractPalette(img, options)
+		if err != nil {
+			t.Fatalf("%+v: %v", tt.alpha, err)
+		}
+		got := palette.Colors()
+		if len(got) != len(tt.want) {
+			t.Fatalf("%+v: got %v, want %v", tt.alpha, got, tt.want)
+		}
+		for i := range tt.want {
+			if got[i] != tt.want[i] {
+				t.Errorf("%+v: color %d is %v, want %v", tt.alpha, i, got[i], tt.want[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package pixelforging
+
+import "math"
+
+// mergeSearchSlack sizes the grid used to find merge candidates, relative to the threshold.
+// CIE94 and CIEDE2000 shrink the chroma and hue differences of saturated colors, so two of
+// them can be within the threshold while being farther apart in CIE76. A bigger slack finds
+// more of those pairs but compares each color with many more candidates, so very saturated
+// colors more than 3 times the threshold apart in CIE76 are left unmerged.
+var mergeSearchSlack = map[DeltaEFormula]float64{
+	DeltaE76:   1,
+	DeltaE94:   3,
+	DeltaE2000: 3,
+}
+
+type labCell [3]int
+
+// mergeSimilarColors merges the colors whose Delta-E to a more frequent color is at most
+// threshold into that color, summing their pixel counts. The palette must be sorted by
+// count (descending), so every group is represented by its most frequent color, which is
+// a color that really exists in the image. The result is sorted by count again.
+func mergeSimilarColors(palette []ColorCount, threshold float64, formula DeltaEFormula) []ColorCount {
+	if threshold <= 0 || len(palette) < 2 {
+		return palette
+	}
+
+	// The candidates are looked for in a grid of the Lab space, so each color is only
+	// compared with the representatives of the neighbouring cells.
+	cellSize := threshold * mergeSearchSlack[formula]
+	if cellSize == 0 {
+		cellSize = threshold
+	}
+	cellOf := func(lab LabColor) labCell {
+		return labCell{
+			int(math.Floor(lab.L / cellSize)),
+			int(math.Floor(lab.A / cellSize)),
+			int(math.Floor(lab.B / cellSize)),
+		}
+	}
+
+	maxDistance := cellSize * cellSize
+
+	merged := make([]ColorCount, 0, len(palette))
+	labs := make([]LabColor, 0, len(palette))
+	grid := make(map[labCell][]int)
+
+	for _, c := range palette {
+		lab := RGBAToLab(c.Color)
+		cell := cellOf(lab)
+
+		nearest, nearestDistance := -1, math.Inf(1)
+		for dl := -1; dl <= 1; dl++ {
+			for da := -1; da <= 1; da++ {
+				for db := -1; db <= 1; db++ {
+					for _, i := range grid[labCell{cell[0] + dl, cell[1] + da, cell[2] + db}] {
+						// Colors with different transparency are never the same color
+						if merged[i].Color.A != c.Color.A || distanceLab(labs[i], lab) > maxDistance {
+							continue
+						}
+						if d := DeltaE(formula, labs[i], lab); d <= threshold && d < nearestDistance {
+							nearest, nearestDistance = i, d
+						}
+					}
+				}
+			}
+		}
+
+		if nearest >= 0 {
+			merged[nearest].Count += c.Count
+			continue
+		}
+		grid[cell] = append(grid[cell], len(merged))
+		merged = append(merged, c)
+		labs = append(labs, lab)
+	}
+
+	sortColorCounts(merged)
+	return merged
+}
@@ -0,0 +1,43 @@
+package pixelforging
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestMergeSimilarColors(t *testing.T) {
+	red := color.RGBA{R: 200, G: 30, B: 30, A: 255}
+	nearRed := color.RGBA{R: 202, G: 31, B: 30, A: 255}
+	translucentRed := color.RGBA{R: 202, G: 31, B: 30, A: 128}
+	blue := color.RGBA{R: 30, G: 30, B: 200, A: 255}
+	nearBlue := color.RGBA{R: 30, G: 32, B: 203, A: 255}
+	// From the most frequent
+	palette := []ColorCount{{red, 10}, {blue, 8}, {nearBlue, 5}, {nearRed, 4}, {translucentRed, 3}}
+
+	tests := []struct {
+		name      string
+		threshold float64
+		formula   DeltaEFormula
+		want      []ColorCount
+	}{
+		{"disabled", 0, DeltaE2000, palette},
+		// Every group keeps its most frequent color, and the colors of another alpha are apart
+		{"ciede2000", 3, DeltaE2000, []ColorCount{{red, 14}, {blue, 13}, {translucentRed, 3}}},
+		{"cie76", 3, DeltaE76, []ColorCount{{red, 14}, {blue, 13}, {translucentRed, 3}}},
+		{"cie94", 3, DeltaE94, []ColorCount{{red, 14}, {blue, 13}, {translucentRed, 3}}},
+		{"too small", 0.1, DeltaE2000, palette},
+	}
+	for _, tt := range tests {
+		got := mergeSimilarColors(append([]ColorCount(nil), palette...), tt.threshold, tt.formula)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range tt.want {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
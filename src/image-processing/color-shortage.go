@@ -0,0 +1,57 @@
+package pixelforging
+
+import (
+	"fmt"
+	"image/color"
+)
+
+// ShortagePolicy selects what is done when the image has fewer colors than requested, like
+// a small sprite asked for a 16 colors palette.
+type ShortagePolicy string
+
+// Supported shortage policies.
+const (
+	// ShortageAvailable returns the colors the image has, so the palette is smaller than requested.
+	ShortageAvailable ShortagePolicy = "available"
+	// ShortagePad fills the missing colors with fully transparent entries, so palettes of a
+	// fixed size, like the 16 slots of a sprite sheet, always have the requested size.
+	// The padding is drawn as empty cells and written as black by the formats without alpha.
+	// Palettes can only be padded up to 256 colors.
+	ShortagePad ShortagePolicy = "pad"
+	// ShortageError returns ErrTooFewColors.
+	ShortageError ShortagePolicy = "error"
+)
+
+// maxPadColors is the most colors a palette can be padded to, the size of the palette of an
+// indexed image, so padding never builds a huge palette of empty entries.
+const maxPadColors = 256
+
+// ParseShortagePolicy validates a shortage policy name. An empty name selects ShortageAvailable.
+func ParseShortagePolicy(name string) (ShortagePolicy, error) {
+	switch policy := ShortagePolicy(name); policy {
+	case "":
+		return ShortageAvailable, nil
+	case ShortageAvailable, ShortagePad, ShortageError:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("%w: unknown color shortage policy %q", ErrInvalidOption, name)
+	}
+}
+
+// applyShortagePolicy checks that the palette has colorNum colors, handling the missing
+// ones as set by the policy, already validated.
+func applyShortagePolicy(palette Palette, colorNum int, policy ShortagePolicy) (Palette, error) {
+	missing := colorNum - len(palette.Entries)
+	if missing <= 0 {
+		return palette, nil
+	}
+	switch policy {
+	case ShortagePad:
+		for range missing {
+			palette.Entries = append(palette.Entries, NewPaletteEntry(color.RGBA{}, 0, palette.TotalPixels))
+		}
+	case ShortageError:
+		return Palette{}, fmt.Errorf("%w: %d colors were requested, only %d could be extracted from the image", ErrTooFewColors, colorNum, len(palette.Entries))
+	}
+	return palette, nil
+}
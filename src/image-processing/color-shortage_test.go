@@ -0,0 +1,79 @@
+package pixelforging
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestShortagePolicies(t *testing.T) {
+	// An image of 3 colors
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	colors := []color.RGBA{{R: 255, A: 255}, {G: 255, A: 255}, {B: 255, A: 255}}
+	for x, c := range colors {
+		img.SetRGBA(x, 0, c)
+		img.SetRGBA(x, 1, c)
+	}
+
+	tests := []struct {
+		policy      ShortagePolicy
+		colorNum    int
+		wantColors  int
+		wantPadding int
+		wantErr     error
+	}{
+		{"", 8, 3, 0, nil},
+		{ShortageAvailable, 8, 3, 0, nil},
+		{ShortagePad, 8, 8, 5, nil},
+		{ShortageError, 8, 0, 0, ErrTooFewColors},
+		// Without a shortage every policy returns the requested colors
+		{ShortagePad, 3, 3, 0, nil},
+		{ShortageError, 2, 2, 0, nil},
+		{"shrink", 8, 0, 0, ErrInvalidOption},
+	}
+	for _, tt := range tests {
+		palette, err := ExtractPalette(img, PaletteOptions{ColorNum: tt.colorNum, Shortage: tt.policy})
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%q, %d colors: got %v, want %v", tt.policy, tt.colorNum, err, tt.wantErr)
+			continue
+		}
+		if len(palette.Entries) != tt.wantColors {
+			t.Errorf("%q, %d colors: got %d colors, want %d", tt.policy, tt.colorNum, len(palette.Entries), tt.wantColors)
+			continue
+		}
+		padding := 0
+		for i, e := range palette.Entries {
+			if e.Color != (color.RGBA{}) {
+				continue
+			}
+			padding++
+			// The padding goes after the colors of the image
+			if i < len(palette.Entries)-tt.wantPadding || e.Count != 0 {
+				t.Errorf("%q, %d colors: entry %d is %+v", tt.policy, tt.colorNum, i, e)
+			}
+		}
+		if padding != tt.wantPadding {
+			t.Errorf("%q, %d colors: got %d transparent colors, want %d", tt.policy, tt.colorNum, padding, tt.wantPadding)
+		}
+	}
+}
+
+func TestShortagePadLimit(t *testing.T) {
+	tests := []struct {
+		colorNum int
+		policy   ShortagePolicy
+		wantErr  error
+	}{
+		{maxPadColors, ShortagePad, nil},
+		{maxPadColors + 1, ShortagePad, ErrInvalidOption},
+		// The other policies never add colors
+		{maxPadColors + 1, ShortageAvailable, nil},
+		{maxPadColors + 1, ShortageError, nil},
+	}
+	for _, tt := range tests {
+		if err := (PaletteOptions{ColorNum: tt.colorNum, Shortage: tt.policy}).Validate(); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%q, %d colors: got %v, want %v", tt.policy, tt.colorNum, err, tt.wantErr)
+		}
+	}
+}
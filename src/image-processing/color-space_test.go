@@ -0,0 +1,41 @@
+package pixelforging
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestRGBAToLab(t *testing.T) {
+	// Reference values of the sRGB primaries under the D65 white point
+	tests := []struct {
+		color color.RGBA
+		want  LabColor
+	}{
+		{color.RGBA{A: 255}, LabColor{L: 0, A: 0, B: 0}},
+		{color.RGBA{R: 255, G: 255, B: 255, A: 255}, LabColor{L: 100, A: 0, B: 0}},
+		{color.RGBA{R: 255, A: 255}, LabColor{L: 53.24, A: 80.09, B: 67.20}},
+		{color.RGBA{G: 255, A: 255}, LabColor{L: 87.73, A: -86.18, B: 83.18}},
+		{color.RGBA{B: 255, A: 255}, LabColor{L: 32.30, A: 79.19, B: -107.86}},
+		{color.RGBA{R: 128, G: 128, B: 128, A: 255}, LabColor{L: 53.59, A: 0, B: 0}},
+	}
+	for _, tt := range tests {
+		got := RGBAToLab(tt.color)
+		if math.Abs(got.L-tt.want.L) > 0.05 || math.Abs(got.A-tt.want.A) > 0.05 || math.Abs(got.B-tt.want.B) > 0.05 {
+			t.Errorf("%v: got %+v, want %+v", tt.color, got, tt.want)
+		}
+	}
+}
+
+func TestLabToRGBARoundTrip(t *testing.T) {
+	for r := 0; r < 256; r += 17 {
+		for g := 0; g < 256; g += 17 {
+			for b := 0; b < 256; b += 17 {
+				c := color.RGBA{R: uint8(r), G: uint8(g), B: uint8(b), A: 200}
+				if got := LabToRGBA(RGBAToLab(c), c.A); got != c {
+					t.Fatalf("%v came back as %v", c, got)
+				}
+			}
+		}
+	}
+}
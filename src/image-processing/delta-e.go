@@ -0,0 +1,133 @@
+package pixelforging
+
+import (
+	"fmt"
+	"math"
+)
+
+// DeltaEFormula selects how the perceptual difference (Delta-E) between two colors is measured.
+type DeltaEFormula string
+
+// Supported Delta-E formulas.
+const (
+	// DeltaE76 is the euclidean distance in CIELAB, fast but less accurate for saturated colors.
+	DeltaE76 DeltaEFormula = "cie76"
+	// DeltaE94 weights chroma and hue differences by the chroma of the colors.
+	DeltaE94 DeltaEFormula = "cie94"
+	// DeltaE2000 is the most accurate formula, correcting also the blue region and neutral colors.
+	DeltaE2000 DeltaEFormula = "ciede2000"
+)
+
+// ParseDeltaEFormula validates a formula name. An empty name selects CIEDE2000.
+func ParseDeltaEFormula(name string) (DeltaEFormula, error) {
+	switch formula := DeltaEFormula(name); formula {
+	case "":
+		return DeltaE2000, nil
+	case DeltaE76, DeltaE94, DeltaE2000:
+		return formula, nil
+	default:
+		return "", fmt.Errorf("%w: unknown Delta-E formula %q", ErrInvalidOption, name)
+	}
+}
+
+// DeltaE returns the difference between two Lab colors measured with the formula.
+// A difference around 2.3 is the smallest that the eye can notice.
+func DeltaE(formula DeltaEFormula, a, b LabColor) float64 {
+	switch formula {
+	case DeltaE94:
+		return deltaE94(a, b)
+	case DeltaE2000:
+		return deltaE2000(a, b)
+	default:
+		return math.Sqrt(distanceLab(a, b))
+	}
+}
+
+// deltaE94 uses the graphic arts constants (kL = 1, K1 = 0.045, K2 = 0.015).
+func deltaE94(a, b LabColor) float64 {
+	c1 := math.Hypot(a.A, a.B)
+	c2 := math.Hypot(b.A, b.B)
+	dL := a.L - b.L
+	dC := c1 - c2
+	da, db := a.A-b.A, a.B-b.B
+	dH2 := math.Max(0, da*da+db*db-dC*dC)
+
+	sC := 1 + 0.045*c1
+	sH := 1 + 0.015*c1
+	return math.Sqrt(dL*dL + (dC/sC)*(dC/sC) + dH2/(sH*sH))
+}
+
+// deltaE2000 follows "The CIEDE2000 Color-Difference Formula" by Sharma, Wu and Dalal.
+func deltaE2000(a, b LabColor) float64 {
+	const pow25To7 = 6103515625.0 // 25^7
+
+	c1 := math.Hypot(a.A, a.B)
+	c2 := math.Hypot(b.A, b.B)
+	cMean7 := math.Pow((c1+c2)/2, 7)
+	g := 0.5 * (1 - math.Sqrt(cMean7/(cMean7+pow25To7)))
+
+	a1, a2 := a.A*(1+g), b.A*(1+g)
+	c1p, c2p := math.Hypot(a1, a.B), math.Hypot(a2, b.B)
+	h1p, h2p := hueAngle(a.B, a1), hueAngle(b.B, a2)
+
+	dLp := b.L - a.L
+	dCp := c2p - c1p
+	dhp := 0.0
+	if c1p*c2p != 0 {
+		dhp = h2p - h1p
+		if dhp > 180 {
+			dhp -= 360
+		} else if dhp < -180 {
+			dhp += 360
+		}
+	}
+	dHp := 2 * math.Sqrt(c1p*c2p) * math.Sin(degToRad(dhp/2))
+
+	lMean := (a.L + b.L) / 2
+	cMeanP := (c1p + c2p) / 2
+	hMeanP := h1p + h2p
+	if c1p*c2p != 0 {
+		switch {
+		case math.Abs(h1p-h2p) <= 180:
+			hMeanP /= 2
+		case h1p+h2p < 360:
+			hMeanP = (hMeanP + 360) / 2
+		default:
+			hMeanP = (hMeanP - 360) / 2
+		}
+	}
+
+	t := 1 - 0.17*math.Cos(degToRad(hMeanP-30)) +
+		0.24*math.Cos(degToRad(2*hMeanP)) +
+		0.32*math.Cos(degToRad(3*hMeanP+6)) -
+		0.20*math.Cos(degToRad(4*hMeanP-63))
+	dTheta := 30 * math.Exp(-math.Pow((hMeanP-275)/25, 2))
+	cMeanP7 := math.Pow(cMeanP, 7)
+	rC := 2 * math.Sqrt(cMeanP7/(cMeanP7+pow25To7))
+	l50 := (lMean - 50) * (lMean - 50)
+	sL := 1 + 0.015*l50/math.Sqrt(20+l50)
+	sC := 1 + 0.045*cMeanP
+	sH := 1 + 0.015*cMeanP*t
+	rT := -math.Sin(degToRad(2*dTheta)) * rC
+
+	l := dLp / sL
+	c := dCp / sC
+	h := dHp / sH
+	return math.Sqrt(l*l + c*c + h*h + rT*c*h)
+}
+
+// hueAngle returns the angle of (x, y) in degrees, in the [0, 360) range.
+func hueAngle(y, x float64) float64 {
+	if x == 0 && y == 0 {
+		return 0
+	}
+	h := math.Atan2(y, x) * 180 / math.Pi
+	if h < 0 {
+		h += 360
+	}
+	return h
+}
+
+func degToRad(deg float64) float64 {
+	return deg * math.Pi / 180
+}
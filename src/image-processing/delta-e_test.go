@@ -0,0 +1,101 @@
+package pixelforging
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// TestDeltaE2000 checks the test data of "The CIEDE2000 Color-Difference Formula:
+// Implementation Notes, Supplementary Test Data, and Mathematical Observations" by Sharma,
+// Wu and Dalal.
+func TestDeltaE2000(t *testing.T) {
+	tests := []struct {
+		a, b LabColor
+		want float64
+	}{
+		{LabColor{50, 2.6772, -79.7751}, LabColor{50, 0, -82.7485}, 2.0425},
+		{LabColor{50, 3.1571, -77.2803}, LabColor{50, 0, -82.7485}, 2.8615},
+		{LabColor{50, 2.8361, -74.0200}, LabColor{50, 0, -82.7485}, 3.4412},
+		{LabColor{50, -1.3802, -84.2814}, LabColor{50, 0, -82.7485}, 1.0000},
+		{LabColor{50, -1.1848, -84.8006}, LabColor{50, 0, -82.7485}, 1.0000},
+		{LabColor{50, -0.9009, -85.5211}, LabColor{50, 0, -82.7485}, 1.0000},
+		{LabColor{50, 0, 0}, LabColor{50, -1, 2}, 2.3669},
+		{LabColor{50, -1, 2}, LabColor{50, 0, 0}, 2.3669},
+		{LabColor{50, 2.4900, -0.0010}, LabColor{50, -2.4900, 0.0009}, 7.1792},
+		{LabColor{50, 2.4900, -0.0010}, LabColor{50, -2.4900, 0.0010}, 7.1792},
+		{LabColor{50, 2.4900, -0.0010}, LabColor{50, -2.4900, 0.0011}, 7.2195},
+		{LabColor{50, 2.4900, -0.0010}, LabColor{50, -2.4900, 0.0012}, 7.2195},
+		{LabColor{50, -0.0010, 2.4900}, LabColor{50, 0.0009, -2.4900}, 4.8045},
+		{LabColor{50, -0.0010, 2.4900}, LabColor{50, 0.0010, -2.4900}, 4.8045},
+		{LabColor{50, -0.0010, 2.4900}, LabColor{50, 0.0011, -2.4900}, 4.7461},
+		{LabColor{50, 2.5000, 0}, LabColor{50, 0, -2.5000}, 4.3065},
+		{LabColor{50, 2.5000, 0}, LabColor{73, 25, -18}, 27.1492},
+		{LabColor{50, 2.5000, 0}, LabColor{61, -5, 29}, 22.8977},
+		{LabColor{50, 2.5000, 0}, LabColor{56, -27, -3}, 31.9030},
+		{LabColor{50, 2.5000, 0}, LabColor{58, 24, 15}, 19.4535},
+		{LabColor{50, 2.5000, 0}, LabColor{50, 3.1736, 0.5854}, 1.0000},
+		{LabColor{50, 2.5000, 0}, LabColor{50, 3.2972, 0}, 1.0000},
+		{LabColor{50, 2.5000, 0}, LabColor{50, 1.8634, 0.5757}, 1.0000},
+		{LabColor{50, 2.5000, 0}, LabColor{50, 3.2592, 0.3350}, 1.0000},
+		{LabColor{60.2574, -34.0099, 36.2677}, LabColor{60.4626, -34.1751, 39.4387}, 1.2644},
+		{LabColor{63.0109, -31.0961, -5.8663}, LabColor{62.8187, -29.7946, -4.0864}, 1.2630},
+		{LabColor{61.2901, 3.7196, -5.3901}, LabColor{61.4292, 2.2480, -4.9620}, 1.8731},
+		{LabColor{35.0831, -44.1164, 3.7933}, LabColor{35.0232, -40.0716, 1.5901}, 1.8645},
+		{LabColor{22.7233, 20.0904, -46.6940}, LabColor{23.0331, 14.9730, -42.5619}, 2.0373},
+		{LabColor{36.4612, 47.8580, 18.3852}, LabColor{36.2715, 50.5065, 21.2231}, 1.4146},
+		{LabColor{90.8027, -2.0831, 1.4410}, LabColor{91.1528, -1.6435, 0.0447}, 1.4441},
+		{LabColor{90.9257, -0.5406, -0.9208}, LabColor{88.6381, -0.8985, -0.7239}, 1.5381},
+		{LabColor{6.7747, -0.2908, -2.4247}, LabColor{5.8714, -0.0985, -2.2286}, 0.6377},
+		{LabColor{2.0776, 0.0795, -1.1350}, LabColor{0.9033, -0.0636, -0.5514}, 0.9082},
+	}
+	for i, tt := range tests {
+		if got := DeltaE(DeltaE2000, tt.a, tt.b); math.Abs(got-tt.want) > 1e-4 {
+			t.Errorf("pair %d: got %.4f, want %.4f", i+1, got, tt.want)
+		}
+		// The formula is symmetric
+		if got := DeltaE(DeltaE2000, tt.b, tt.a); math.Abs(got-tt.want) > 1e-4 {
+			t.Errorf("pair %d reversed: got %.4f, want %.4f", i+1, got, tt.want)
+		}
+	}
+}
+
+func TestDeltaE(t *testing.T) {
+	tests := []struct {
+		formula DeltaEFormula
+		a, b    LabColor
+		want    float64
+	}{
+		{DeltaE76, LabColor{50, 0, 0}, LabColor{53, 4, 0}, 5},
+		{DeltaE76, LabColor{50, 10, -10}, LabColor{50, 10, -10}, 0},
+		// Lightness differences are not weighted by CIE94
+		{DeltaE94, LabColor{50, 0, 0}, LabColor{60, 0, 0}, 10},
+		// Chroma differences are divided by 1 + 0.045 * the chroma of the first color
+		{DeltaE94, LabColor{50, 20, 0}, LabColor{50, 10, 0}, 10 / 1.9},
+	}
+	for _, tt := range tests {
+		if got := DeltaE(tt.formula, tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s %+v %+v: got %v, want %v", tt.formula, tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestParseDeltaEFormula(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    DeltaEFormula
+		wantErr error
+	}{
+		{"", DeltaE2000, nil},
+		{"cie76", DeltaE76, nil},
+		{"cie94", DeltaE94, nil},
+		{"ciede2000", DeltaE2000, nil},
+		{"CIE76", "", ErrInvalidOption},
+	}
+	for _, tt := range tests {
+		got, err := ParseDeltaEFormula(tt.name)
+		if got != tt.want || !errors.Is(err, tt.wantErr) {
+			t.Errorf("%q: got %q, %v, want %q, %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package pixelforging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// DitherMethod selects how the colors lost when an image is reduced to a palette are compensated.
+type DitherMethod string
+
+// Supported dithering methods.
+const (
+	// DitherNone maps every pixel to its nearest palette color, like RemapImage.
+	DitherNone DitherMethod = "none"
+	// Error-diffusion methods spread the difference between a pixel and its palette
+	// color over the neighbours that were not processed yet.
+	DitherFloydSteinberg    DitherMethod = "floyd-steinberg"
+	DitherJarvisJudiceNinke DitherMethod = "jarvis-judice-ninke"
+	DitherStucki            DitherMethod = "stucki"
+	DitherSierra            DitherMethod = "sierra"
+	// DitherAtkinson diffuses only 3/4 of the error, keeping more contrast than the others.
+	DitherAtkinson DitherMethod = "atkinson"
+)
+
+// DitherOptions configures DitherImage.
+type DitherOptions struct {
+	Method DitherMethod
+	// Strength scales the diffused error, or the offsets of ordered dithering, from 0 to 1.
+	// 0 uses 1 (the full error).
+	Strength float64
+	// RasterScan processes every line from left to right. By default the lines alternate
+	// direction (serpentine scanning), which avoids the diagonal artifacts of error diffusion.
+	RasterScan bool
+	// Matrix is the threshold map of DitherCustomMatrix: a rectangle of values >= 0 where
+	// the value v of a cell stands for the threshold (v+0.5)/(max+1), like a Bayer matrix.
+	Matrix [][]float64
+}
+
+// diffusionTap is a neighbour that receives weight/divisor of the error of a pixel.
+type diffusionTap struct {
+	dx, dy int
+	weight float64
+}
+
+// diffusionKernel is the error distribution of an error-diffusion method.
+type diffusionKernel struct {
+	divisor float64
+	taps    []diffusionTap
+}
+
+var diffusionKernels = map[DitherMethod]diffusionKernel{
+	DitherFloydSteinberg: {16, []diffusionTap{
+		{1, 0, 7},
+		{-1, 1, 3}, {0, 1, 5}, {1, 1, 1},
+	}},
+	DitherJarvisJudiceNinke: {48, []diffusionTap{
+		{1, 0, 7}, {2, 0, 5},
+		{-2, 1, 3}, {-1, 1, 5}, {0, 1, 7}, {1, 1, 5}, {2, 1, 3},
+		{-2, 2, 1}, {-1, 2, 3}, {0, 2, 5}, {1, 2, 3}, {2, 2, 1},
+	}},
+	DitherStucki: {42, []diffusionTap{
+		{1, 0, 8}, {2, 0, 4},
+		{-2, 1, 2}, {-1, 1, 4}, {0, 1, 8}, {1, 1, 4}, {2, 1, 2},
+		{-2, 2, 1}, {-1, 2, 2}, {0, 2, 4}, {1, 2, 2}, {2, 2, 1},
+	}},
+	DitherSierra: {32, []diffusionTap{
+		{1, 0, 5}, {2, 0, 3},
+		{-2, 1, 2}, {-1, 1, 4}, {0, 1, 5}, {1, 1, 4}, {2, 1, 2},
+		{-1, 2, 2}, {0, 2, 3}, {1, 2, 2},
+	}},
+	DitherAtkinson: {8, []diffusionTap{
+		{1, 0, 1}, {2, 0, 1},
+		{-1, 1, 1}, {0, 1, 1}, {1, 1, 1},
+		{0, 2, 1},
+	}},
+}
+
+// ParseDitherMethod validates a dithering method name. An empty name selects DitherNone.
+func ParseDitherMethod(name string) (DitherMethod, error) {
+	method := DitherMethod(name)
+	if method == "" || method == DitherNone {
+		return DitherNone, nil
+	}
+	if _, ok := diffusionKernels[method]; ok || isOrderedDither(method) {
+		return method, nil
+	}
+	return "", fmt.Errorf("%w: unknown dithering method %q", ErrInvalidOption, name)
+}
+
+// DitherImage reduces the image to the colors of the palette like RemapImage, compensating
+// the lost colors with the dithering method of the options. Fully transparent pixels are
+// kept transparent.
+func DitherImage(img image.Image, palette Palette, metric DistanceMetric, options DitherOptions) (*image.NRGBA, error) {
+	method, err := ParseDitherMethod(string(options.Method))
+	if err != nil {
+		return nil, err
+	}
+	if options.Strength < 0 || options.Strength > 1 {
+		return nil, fmt.Errorf("%w: the dithering strength should be between 0 and 1, got %v", ErrInvalidOption, options.Strength)
+	}
+	if method == DitherNone {
+		return RemapImage(img, palette, metric)
+	}
+	if len(palette.Entries) == 0 {
+		return nil, fmt.Errorf("%w: the palette has no colors", ErrTooFewColors)
+	}
+	strength := options.Strength
+	if strength == 0 {
+		strength = 1
+	}
+	if isOrderedDither(method) {
+		matrix, err := thresholdMap(method, options.Matrix)
+		if err != nil {
+			return nil, err
+		}
+		return orderedDither(img, palette.Colors(), metric, matrix, strength), nil
+	}
+	return diffuseError(img, palette.Colors(), metric, diffusionKernels[method], strength, !options.RasterScan), nil
+}
+
+// diffuseError maps the pixels to the colors one by one, adding to each pixel the error
+// diffused by the pixels already processed. Only the rows reached by the kernel are kept
+// in memory.
+func diffuseError(img image.Image, colors []color.RGBA, metric DistanceMetric, kernel diffusionKernel, strength float64, serpentine bool) *image.NRGBA {
+	bounds := img.Bounds()
+	out := image.NewNRGBA(bounds)
+	width := bounds.Dx()
+	matcher := newColorMatcher(colors, metric)
+	cache := make(map[color.RGBA]color.RGBA)
+
+	rowsKept := 1
+	for _, tap := range kernel.taps {
+		rowsKept = max(rowsKept, tap.dy+1)
+	}
+	// errs[i][3*x+c] is the error accumulated for channel c of the pixel x of the row y+i
+	errs := make([][]float64, rowsKept)
+	for i := range errs {
+		errs[i] = make([]float64, width*3)
+	}
+
+	read := newRowReader(img)
+	row := make([]color.RGBA, width)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		read(y, row)
+		reverse := serpentine && (y-bounds.Min.Y)%2 == 1
+		for i := 0; i < width; i++ {
+			x := i
+			if reverse {
+				x = width - 1 - i
+			}
+			c := row[x]
+			if c.A == 0 {
+				continue
+			}
+
+			var value [3]float64
+			for ch, v := range [3]uint8{c.R, c.G, c.B} {
+				value[ch] = float64(v) + errs[0][3*x+ch]
+			}
+			wanted := color.RGBA{to8Bit(value[0] / 255), to8Bit(value[1] / 255), to8Bit(value[2] / 255), c.A}
+			mapped, ok := cache[wanted]
+			if !ok {
+				if len(cache) == remapCacheSize {
+					clear(cache)
+				}
+				mapped = colors[matcher.nearest(wanted)]
+				cache[wanted] = mapped
+			}
+			out.SetNRGBA(bounds.Min.X+x, y, color.NRGBA(mapped))
+
+			// The error is measured from the clamped color, so it can not grow without bounds
+			diff := [3]float64{
+				(float64(wanted.R) - float64(mapped.R)) * strength / kernel.divisor,
+				(float64(wanted.G) - float64(mapped.G)) * strength / kernel.divisor,
+				(float64(wanted.B) - float64(mapped.B)) * strength / kernel.divisor,
+			}
+			for _, tap := range kernel.taps {
+				nx := x + tap.dx
+				if reverse {
+					nx = x - tap.dx
+				}
+				if nx < 0 || nx >= width {
+					continue
+				}
+				row := errs[tap.dy]
+				for ch := range diff {
+					row[3*nx+ch] += diff[ch] * tap.weight
+				}
+			}
+		}
+
+		// Move to the next row, reusing the buffer of the finished one
+		finished := errs[0]
+		copy(errs, errs[1:])
+		clear(finished)
+		errs[len(errs)-1] = finished
+	}
+	return out
+}
@@ -0,0 +1,192 @@
+package pixelforging
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// grayImage returns an opaque image filled with a single gray.
+func grayImage(width, height int, gray uint8) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			img.SetRGBA(x, y, color.RGBA{R: gray, G: gray, B: gray, A: 255})
+		}
+	}
+	return img
+}
+
+// blackAndWhite is a palette of the two colors that dithering mixes to draw the grays.
+func blackAndWhite() Palette {
+	return Palette{Entries: []PaletteEntry{
+		NewPaletteEntry(color.RGBA{A: 255}, 0, 0),
+		NewPaletteEntry(color.RGBA{R: 255, G: 255, B: 255, A: 255}, 0, 0),
+	}}
+}
+
+// errorDiffusionMethods are the methods of diffusionKernels.
+var errorDiffusionMethods = []DitherMethod{DitherFloydSteinberg, DitherJarvisJudiceNinke, DitherStucki, DitherSierra, DitherAtkinson}
+
+func TestDiffusionKernels(t *testing.T) {
+	tests := []struct {
+		method DitherMethod
+		// share of the error diffused to the neighbours
+		want float64
+	}{
+		{DitherFloydSteinberg, 1},
+		{DitherJarvisJudiceNinke, 1},
+		{DitherStucki, 1},
+		{DitherSierra, 1},
+		{DitherAtkinson, 0.75},
+	}
+	for _, tt := range tests {
+		kernel := diffusionKernels[tt.method]
+		sum := 0.0
+		for _, tap := range kernel.taps {
+			if tap.dy < 0 || (tap.dy == 0 && tap.dx <= 0) {
+				t.Errorf("%s: the tap (%d, %d) was already processed", tt.method, tap.dx, tap.dy)
+			}
+			sum += tap.weight
+		}
+		if got := sum / kernel.divisor; got != tt.want {
+			t.Errorf("%s: diffuses %v of the error, want %v", tt.method, got, tt.want)
+		}
+	}
+}
+
+func TestDitherImageIsDeterministic(t *testing.T) {
+	img := randomImage(24, 16, 5)
+	palette := testPalette()
+	for _, method := range errorDiffusionMethods {
+		for _, raster := range []bool{false, true} {
+			options := DitherOptions{Method: method, Strength: 0.8, RasterScan: raster}
+			first, err := DitherImage(img, palette, MetricWeightedRGB, options)
+			if err != nil {
+				t.Fatalf("%s: %v", method, err)
+			}
+			second, err := DitherImage(img, palette, MetricWeightedRGB, options)
+			if err != nil {
+				t.Fatalf("%s: %v", method, err)
+			}
+			if string(first.Pix) != string(second.Pix) {
+				t.Errorf("%s, raster %v: two runs gave different images", method, raster)
+			}
+		}
+	}
+}
+
+func TestDitherImageUsesPaletteColors(t *testing.T) {
+	img := randomImage(20, 20, 6)
+	img.SetRGBA(3, 7, color.RGBA{})
+	palette := testPalette()
+	inPalette := make(map[color.NRGBA]bool)
+	for _, c := range palette.Colors() {
+		inPalette[color.NRGBA(c)] = true
+	}
+	for _, method := range append(errorDiffusionMethods, DitherNone) {
+		out, err := DitherImage(img, palette, MetricRGB, DitherOptions{Method: method})
+		if err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+		for y := range 20 {
+			for x := range 20 {
+				c := out.NRGBAAt(x, y)
+				if x == 3 && y == 7 {
+					if c != (color.NRGBA{}) {
+						t.Errorf("%s: the transparent pixel became %v", method, c)
+					}
+				} else if !inPalette[c] {
+					t.Errorf("%s: pixel (%d, %d) is %v, not a palette color", method, x, y, c)
+				}
+			}
+		}
+	}
+}
+
+func TestDitherImageKeepsTheMeanGray(t *testing.T) {
+	const size = 32
+	img := grayImage(size, size, 64)
+	tests := []struct {
+		method             DitherMethod
+		minShare, maxShare float64
+	}{
+		// A quarter of the pixels should be white
+		{DitherFloydSteinberg, 0.2, 0.3},
+		{DitherJarvisJudiceNinke, 0.2, 0.3},
+		{DitherStucki, 0.2, 0.3},
+		{DitherSierra, 0.2, 0.3},
+		// Atkinson drops a quarter of the error, so fewer grays reach white
+		{DitherAtkinson, 0.1, 0.25},
+	}
+	for _, tt := range tests {
+		out, err := DitherImage(img, blackAndWhite(), MetricRGB, DitherOptions{Method: tt.method})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.method, err)
+		}
+		white := 0
+		for i := 0; i < len(out.Pix); i += 4 {
+			if out.Pix[i] == 255 {
+				white++
+			}
+		}
+		share := float64(white) / (size * size)
+		if share < tt.minShare || share > tt.maxShare {
+			t.Errorf("%s: %.2f of the pixels are white, want between %v and %v", tt.method, share, tt.minShare, tt.maxShare)
+		}
+	}
+
+	// Without dithering, every pixel is mapped to black
+	out, err := DitherImage(img, blackAndWhite(), MetricRGB, DitherOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < len(out.Pix); i += 4 {
+		if out.Pix[i] != 0 {
+			t.Fatalf("DitherNone drew a white pixel")
+		}
+	}
+}
+
+func TestDitherImageScanOrder(t *testing.T) {
+	img := randomImage(16, 8, 7)
+	palette := testPalette()
+	serpentine, err := DitherImage(img, palette, MetricRGB, DitherOptions{Method: DitherFloydSteinberg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raster, err := DitherImage(img, palette, MetricRGB, DitherOptions{Method: DitherFloydSteinberg, RasterScan: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first line is processed from left to right by both orders
+	rowBytes := 16 * 4
+	if string(serpentine.Pix[:rowBytes]) != string(raster.Pix[:rowBytes]) {
+		t.Errorf("the first line differs between the serpentine and raster scans")
+	}
+	if string(serpentine.Pix) == string(raster.Pix) {
+		t.Errorf("the serpentine and raster scans gave the same image")
+	}
+}
+
+func TestDitherImageErrors(t *testing.T) {
+	img := randomImage(4, 4, 8)
+	tests := []struct {
+		name    string
+		palette Palette
+		options DitherOptions
+		wantErr error
+	}{
+		{"unknown method", testPalette(), DitherOptions{Method: "halftone"}, ErrInvalidOption},
+		{"negative strength", testPalette(), DitherOptions{Method: DitherSierra, Strength: -0.1}, ErrInvalidOption},
+		{"strength over 1", testPalette(), DitherOptions{Method: DitherSierra, Strength: 1.5}, ErrInvalidOption},
+		{"empty palette", Palette{}, DitherOptions{Method: DitherAtkinson}, ErrTooFewColors},
+		{"empty palette without dithering", Palette{}, DitherOptions{}, ErrTooFewColors},
+	}
+	for _, tt := range tests {
+		if _, err := DitherImage(img, tt.palette, MetricRGB, tt.options); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package pixelforging
+
+import "errors"
+
+// Errors returned by the package, wrapped with the details of each case. Check them with
+// errors.Is to tell a bad input from a failure of the library.
+var (
+	// ErrTooFewColors is returned when the image or the palette has no colors to work with.
+	ErrTooFewColors = errors.New("too few colors")
+	// ErrUnsupportedFormat is returned when an image or palette format is unknown or can not
+	// be read or written.
+	ErrUnsupportedFormat = errors.New("unsupported format")
+	// ErrInvalidDimensions is returned when a size, like the width of the palette blocks, is
+	// out of range.
+	ErrInvalidDimensions = errors.New("invalid dimensions")
+	// ErrInvalidOption is returned when an option, like the palette algorithm or the
+	// distance metric, is unknown or out of range.
+	ErrInvalidOption = errors.New("invalid option")
+)
@@ -0,0 +1,188 @@
+package pixelforging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// histogramMaxBits is the number of bits of each channel of a color.
+const histogramMaxBits = 8
+
+// Histogram counts the pixels of each color of one or more images without keeping the
+// pixels themselves. The RGB channels can be bucketed to fewer bits, like the 5-5-5 colors
+// of old consoles, so similar colors share a bucket and the memory used stays bounded even
+// for huge photos. The zero value is an empty histogram of exact (8 bits) colors.
+type Histogram struct {
+	bits   int
+	counts map[color.RGBA]int
+	// sums holds the sum of the channels of the colors counted in each bucket, used to
+	// report the mean color of the bucket. It is only used when bits < 8.
+	sums map[color.RGBA]*[4]int
+	// first holds the position, in the order the pixels were added, of the first pixel of
+	// each color, used to sort the palettes by the first appearance of their colors.
+	first map[color.RGBA]int
+	// next is the position of the next pixel added
+	next  int
+	total int
+}
+
+// NewHistogram returns an empty histogram that keeps bits bits, from 1 to 8, of each RGB
+// channel. 0 keeps the exact colors (8 bits).
+func NewHistogram(bits int) (*Histogram, error) {
+	if bits == 0 {
+		bits = histogramMaxBits
+	}
+	if bits < 1 || bits > histogramMaxBits {
+		return nil, fmt.Errorf("%w: the histogram bits should be between 1 and %d, got %d", ErrInvalidOption, histogramMaxBits, bits)
+	}
+	return &Histogram{bits: bits}, nil
+}
+
+// Bits returns the number of bits kept of each RGB channel.
+func (h *Histogram) Bits() int {
+	if h.bits == 0 {
+		return histogramMaxBits
+	}
+	return h.bits
+}
+
+// Total returns the number of pixels counted.
+func (h *Histogram) Total() int {
+	return h.total
+}
+
+// Len returns the number of colors, or buckets, of the histogram.
+func (h *Histogram) Len() int {
+	return len(h.counts)
+}
+
+// Add counts n pixels of the color. Fully transparent colors are not counted.
+func (h *Histogram) Add(c color.RGBA, n int) {
+	if c.A == 0 || n <= 0 {
+		return
+	}
+	if h.counts == nil {
+		h.counts = make(map[color.RGBA]int)
+		h.first = make(map[color.RGBA]int)
+	}
+	position := h.next
+	h.next += n
+	if bits := h.Bits(); bits < histogramMaxBits {
+		mask := uint8(0xff << (histogramMaxBits - bits))
+		bucket := color.RGBA{R: c.R & mask, G: c.G & mask, B: c.B & mask, A: c.A}
+		if h.sums == nil {
+			h.sums = make(map[color.RGBA]*[4]int)
+		}
+		sum := h.sums[bucket]
+		if sum == nil {
+			sum = &[4]int{}
+			h.sums[bucket] = sum
+		}
+		sum[0] += int(c.R) * n
+		sum[1] += int(c.G) * n
+		sum[2] += int(c.B) * n
+		sum[3] += int(c.A) * n
+		c = bucket
+	}
+	if _, ok := h.counts[c]; !ok {
+		h.first[c] = position
+	}
+	h.counts[c] += n
+	h.total += n
+}
+
+// AddImage counts the pixels of the image, with their transparency handled as set by the
+// alpha options. The image is read in parallel, every worker counting its band of rows in
+// its own histogram, and the histograms are merged at the end.
+func (h *Histogram) AddImage(img image.Image, alpha AlphaOptions) error {
+	img, err := applyAlphaPolicy(img, alpha)
+	if err != nil {
+		return err
+	}
+
+	bands := scanRows(img, func() *Histogram {
+		return &Histogram{bits: h.Bits()}
+	}, func(band *Histogram, y int, row []color.RGBA) {
+		// Runs of the same color are common, specially in pixel art, and are counted at once
+		run, runLength := color.RGBA{}, 0
+		for _, c := range row {
+			if c == run {
+				runLength++
+				continue
+			}
+			band.Add(run, runLength)
+			run, runLength = c, 1
+		}
+		band.Add(run, runLength)
+	})
+	for _, band := range bands {
+		// The first band is adopted as is, instead of copied
+		if h.counts == nil {
+			h.counts, h.sums, h.first, h.next, h.total = band.counts, band.sums, band.first, band.next, band.total
+			continue
+		}
+		if err := h.Merge(band); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Merge adds the counts of the other histogram, that must keep the same number of bits,
+// so a single palette can be extracted from many images. The pixels of the other histogram
+// are seen as added after the pixels of h.
+func (h *Histogram) Merge(other *Histogram) error {
+	if other.Bits() != h.Bits() {
+		return fmt.Errorf("%w: can not merge a histogram of %d bits into one of %d bits", ErrInvalidOption, other.Bits(), h.Bits())
+	}
+	if h.counts == nil {
+		h.counts = make(map[color.RGBA]int, len(other.counts))
+		h.first = make(map[color.RGBA]int, len(other.counts))
+	}
+	for c, n := range other.counts {
+		if _, ok := h.counts[c]; !ok {
+			h.first[c] = h.next + other.first[c]
+		}
+		h.counts[c] += n
+	}
+	for bucket, otherSum := range other.sums {
+		if h.sums == nil {
+			h.sums = make(map[color.RGBA]*[4]int, len(other.sums))
+		}
+		sum := h.sums[bucket]
+		if sum == nil {
+			sum = &[4]int{}
+			h.sums[bucket] = sum
+		}
+		for i := range sum {
+			sum[i] += otherSum[i]
+		}
+	}
+	h.next += other.next
+	h.total += other.total
+	return nil
+}
+
+// Colors returns the colors of the histogram with their pixel counts, from the most
+// frequent. Each bucket is represented by the mean color of its pixels.
+func (h *Histogram) Colors() []ColorCount {
+	colors := make([]ColorCount, 0, len(h.counts))
+	for c, n := range h.counts {
+		colors = append(colors, ColorCount{Color: h.meanColor(c, n), Count: n})
+	}
+	sortColorCounts(colors)
+	return colors
+}
+
+// meanColor returns the mean color of the n pixels of the bucket.
+func (h *Histogram) meanColor(bucket color.RGBA, n int) color.RGBA {
+	sum := h.sums[bucket]
+	if sum == nil {
+		return bucket
+	}
+	mean := func(v int) uint8 {
+		return uint8((v + n/2) / n)
+	}
+	return color.RGBA{R: mean(sum[0]), G: mean(sum[1]), B: mean(sum[2]), A: mean(sum[3])}
+}
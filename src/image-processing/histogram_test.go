@@ -0,0 +1,188 @@
+package pixelforging
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestNewHistogram(t *testing.T) {
+	tests := []struct {
+		bits     int
+		wantBits int
+		wantErr  error
+	}{
+		{0, 8, nil},
+		{1, 1, nil},
+		{5, 5, nil},
+		{8, 8, nil},
+		{-1, 0, ErrInvalidOption},
+		{9, 0, ErrInvalidOption},
+	}
+	for _, tt := range tests {
+		h, err := NewHistogram(tt.bits)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%d bits: got %v, want %v", tt.bits, err, tt.wantErr)
+			continue
+		}
+		if err == nil && h.Bits() != tt.wantBits {
+			t.Errorf("%d bits: the histogram keeps %d bits, want %d", tt.bits, h.Bits(), tt.wantBits)
+		}
+	}
+}
+
+func TestHistogramAdd(t *testing.T) {
+	red, blue := color.RGBA{R: 255, A: 255}, color.RGBA{B: 255, A: 128}
+	// The zero value is an empty histogram of exact colors
+	var h Histogram
+	h.Add(red, 3)
+	h.Add(blue, 2)
+	h.Add(red, 1)
+	h.Add(color.RGBA{R: 9}, 5)
+	h.Add(blue, 0)
+	h.Add(blue, -4)
+
+	want := []ColorCount{{red, 4}, {blue, 2}}
+	got := h.Colors()
+	if h.Total() != 6 || h.Len() != 2 || len(got) != len(want) {
+		t.Fatalf("got %v with %d pixels, want %v with 6", got, h.Total(), want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("color %d is %v, want %v", i, got[i], want[i])
+		}
+	}
+	if h.first[red] != 0 || h.first[blue] != 3 {
+		t.Errorf("the colors were first seen at %d and %d, want 0 and 3", h.first[red], h.first[blue])
+	}
+}
+
+func TestHistogramBuckets(t *testing.T) {
+	tests := []struct {
+		name   string
+		bits   int
+		colors []ColorCount
+		want   []ColorCount
+	}{
+		{
+			"same bucket, mean color",
+			4,
+			[]ColorCount{{color.RGBA{R: 0x10, G: 0x20, B: 0x30, A: 255}, 1}, {color.RGBA{R: 0x1e, G: 0x2e, B: 0x3e, A: 255}, 3}},
+			[]ColorCount{{color.RGBA{R: 0x1b, G: 0x2b, B: 0x3b, A: 255}, 4}},
+		},
+		{
+			"different buckets",
+			4,
+			[]ColorCount{{color.RGBA{R: 0x10, A: 255}, 2}, {color.RGBA{R: 0x20, A: 255}, 1}},
+			[]ColorCount{{color.RGBA{R: 0x10, A: 255}, 2}, {color.RGBA{R: 0x20, A: 255}, 1}},
+		},
+		{
+			"different alphas are not bucketed",
+			1,
+			[]ColorCount{{color.RGBA{R: 200, A: 255}, 2}, {color.RGBA{R: 200, A: 254}, 1}},
+			[]ColorCount{{color.RGBA{R: 200, A: 255}, 2}, {color.RGBA{R: 200, A: 254}, 1}},
+		},
+		{
+			"exact colors",
+			8,
+			[]ColorCount{{color.RGBA{R: 0x10, A: 255}, 2}, {color.RGBA{R: 0x11, A: 255}, 1}},
+			[]ColorCount{{color.RGBA{R: 0x10, A: 255}, 2}, {color.RGBA{R: 0x11, A: 255}, 1}},
+		},
+	}
+	for _, tt := range tests {
+		h, err := NewHistogram(tt.bits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range tt.colors {
+			h.Add(c.Color, c.Count)
+		}
+		got := h.Colors()
+		if len(got) != len(tt.want) {
+			t.Fatalf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+		for i := range tt.want {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: color %d is %v, want %v", tt.name, i, got[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestHistogramMerge(t *testing.T) {
+	red, green, blue := color.RGBA{R: 255, A: 255}, color.RGBA{G: 255, A: 255}, color.RGBA{B: 255, A: 255}
+	h := newTestHistogram(t, ColorCount{red, 2}, ColorCount{green, 1})
+	other := newTestHistogram(t, ColorCount{blue, 4}, ColorCount{red, 1})
+	if err := h.Merge(other); err != nil {
+		t.Fatal(err)
+	}
+	if h.Total() != 8 || h.Len() != 3 {
+		t.Fatalf("got %d colors and %d pixels, want 3 and 8", h.Len(), h.Total())
+	}
+	// The pixels of the merged histogram come after the 3 pixels of h
+	wantFirst := map[color.RGBA]int{red: 0, green: 2, blue: 3}
+	for c, want := range wantFirst {
+		if h.first[c] != want {
+			t.Errorf("%v was first seen at %d, want %d", c, h.first[c], want)
+		}
+	}
+	if got := h.Colors()[0]; got != (ColorCount{blue, 4}) {
+		t.Errorf("the most frequent color is %v, want %v", got, ColorCount{blue, 4})
+	}
+
+	bucketed, err := NewHistogram(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Merge(bucketed); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("merging 5 bits into 8 bits: got %v, want ErrInvalidOption", err)
+	}
+}
+
+func TestHistogramAddImage(t *testing.T) {
+	img := randomImage(40, 30, 13)
+	// Runs of the same color and a transparent hole
+	for x := range 10 {
+		img.SetRGBA(x, 5, color.RGBA{R: 1, G: 2, B: 3, A: 255})
+		img.SetRGBA(x, 6, color.RGBA{})
+	}
+	for _, bits := range []int{8, 5, 2} {
+		h, err := NewHistogram(bits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := h.AddImage(img, AlphaOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		want, _ := NewHistogram(bits)
+		for y := range 30 {
+			for x := range 40 {
+				want.Add(img.RGBAAt(x, y), 1)
+			}
+		}
+
+		if h.Total() != 40*30-10 || h.Total() != want.Total() {
+			t.Errorf("%d bits: counted %d pixels, want %d", bits, h.Total(), want.Total())
+		}
+		gotColors, wantColors := h.Colors(), want.Colors()
+		if len(gotColors) != len(wantColors) {
+			t.Fatalf("%d bits: got %d colors, want %d", bits, len(gotColors), len(wantColors))
+		}
+		for i := range wantColors {
+			if gotColors[i] != wantColors[i] {
+				t.Errorf("%d bits: color %d is %v, want %v", bits, i, gotColors[i], wantColors[i])
+			}
+		}
+		for c, position := range want.first {
+			if h.first[c] != position {
+				t.Errorf("%d bits: %v was first seen at %d, want %d", bits, c, h.first[c], position)
+			}
+		}
+	}
+
+	var h Histogram
+	if err := h.AddImage(image.NewRGBA(image.Rect(0, 0, 2, 2)), AlphaOptions{Policy: "invert"}); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("unknown alpha policy: got %v, want ErrInvalidOption", err)
+	}
+}
@@ -0,0 +1,66 @@
+package pixelforging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// maxIndexedColors is the most colors an indexed PNG or GIF can have.
+const maxIndexedColors = 256
+
+// ToPaletted converts an image reduced to the palette, like the ones returned by RemapImage
+// and DitherImage, to an *image.Paletted whose colors are the palette colors in the same
+// order, so it is saved as an indexed PNG (PLTE and tRNS chunks) or GIF.
+// Pixels whose color is not in the palette use the nearest one. When the image has
+// transparent pixels and the palette has no transparent color, one is added at the end.
+func ToPaletted(img image.Image, palette Palette) (*image.Paletted, error) {
+	if len(palette.Entries) == 0 {
+		return nil, fmt.Errorf("%w: the palette has no colors", ErrTooFewColors)
+	}
+	colors := palette.Colors()
+	if len(colors) > maxIndexedColors {
+		return nil, fmt.Errorf("%w: indexed images can have up to %d colors, the palette has %d", ErrInvalidOption, maxIndexedColors, len(colors))
+	}
+
+	indexes := make(map[color.RGBA]uint8, len(colors))
+	transparent := -1
+	for i := len(colors) - 1; i >= 0; i-- {
+		indexes[colors[i]] = uint8(i)
+		if colors[i].A == 0 {
+			transparent = i
+		}
+	}
+
+	bounds := img.Bounds()
+	out := image.NewPaletted(bounds, make(color.Palette, len(colors)))
+	for i, c := range colors {
+		out.Palette[i] = color.NRGBA(c)
+	}
+	matcher := newColorMatcher(colors, MetricWeightedRGB)
+	read := newRowReader(img)
+	row := make([]color.RGBA, bounds.Dx())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		read(y, row)
+		for i, c := range row {
+			x := bounds.Min.X + i
+			index, ok := indexes[c]
+			if !ok && c.A == 0 {
+				if transparent < 0 {
+					if len(out.Palette) == maxIndexedColors {
+						return nil, fmt.Errorf("%w: indexed images can have up to %d colors, the palette and the transparent color have %d", ErrInvalidOption, maxIndexedColors, maxIndexedColors+1)
+					}
+					transparent = len(out.Palette)
+					out.Palette = append(out.Palette, color.NRGBA{})
+				}
+				index, ok = uint8(transparent), true
+			}
+			if !ok {
+				index = uint8(matcher.nearest(c))
+				indexes[c] = index
+			}
+			out.SetColorIndex(x, y, index)
+		}
+	}
+	return out, nil
+}
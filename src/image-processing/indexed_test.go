@@ -0,0 +1,104 @@
+package pixelforging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"testing"
+)
+
+func TestToPaletted(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	green := color.RGBA{G: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+	palette := Palette{Entries: []PaletteEntry{
+		NewPaletteEntry(blue, 0, 0),
+		NewPaletteEntry(red, 0, 0),
+		NewPaletteEntry(green, 0, 0),
+	}}
+
+	tests := []struct {
+		name        string
+		pixels      []color.RGBA
+		wantIndexes []uint8
+		wantColors  int
+	}{
+		{"palette colors", []color.RGBA{red, green, blue, red}, []uint8{1, 2, 0, 1}, 3},
+		{"nearest color", []color.RGBA{{R: 250, G: 10, A: 255}, {B: 200, A: 255}, green, green}, []uint8{1, 0, 2, 2}, 3},
+		{"transparent pixel", []color.RGBA{red, {}, blue, {}}, []uint8{1, 3, 0, 3}, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := image.NewRGBA(image.Rect(0, 0, len(tt.pixels), 1))
+			for x, c := range tt.pixels {
+				img.SetRGBA(x, 0, c)
+			}
+			paletted, err := ToPaletted(img, palette)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(paletted.Palette) != tt.wantColors {
+				t.Fatalf("got %d colors, want %d", len(paletted.Palette), tt.wantColors)
+			}
+			// The palette keeps its order, so the indexes of the file match the palette
+			for i, e := range palette.Entries {
+				if paletted.Palette[i] != color.NRGBA(e.Color) {
+					t.Errorf("color %d is %v, want %v", i, paletted.Palette[i], e.Color)
+				}
+			}
+			for x, want := range tt.wantIndexes {
+				if got := paletted.ColorIndexAt(x, 0); got != want {
+					t.Errorf("pixel %d has index %d, want %d", x, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestToPalettedColorLimit(t *testing.T) {
+	full := sequentialPalette(maxIndexedColors)
+	transparent := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	if _, err := ToPaletted(transparent, full); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("%d colors and a transparent pixel: got %v, want ErrInvalidOption", maxIndexedColors, err)
+	}
+	if _, err := ToPaletted(transparent, sequentialPalette(maxIndexedColors+1)); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("%d colors: got %v, want ErrInvalidOption", maxIndexedColors+1, err)
+	}
+}
+
+func TestEncodeIndexedImage(t *testing.T) {
+	palette := testPalette()
+	paletted, err := ToPaletted(randomImage(8, 8, 10), palette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoders := map[string]func(*bytes.Buffer) (image.Image, error){
+		"png": func(b *bytes.Buffer) (image.Image, error) { return png.Decode(b) },
+		"gif": func(b *bytes.Buffer) (image.Image, error) { return gif.Decode(b) },
+	}
+	for format, decode := range decoders {
+		var buf bytes.Buffer
+		if err := EncodeImage(&buf, paletted, format); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		img, err := decode(&buf)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		got, ok := img.(*image.Paletted)
+		if !ok {
+			t.Fatalf("%s: decoded a %T, want an indexed image", format, img)
+		}
+		for i, e := range palette.Entries {
+			if color.NRGBAModel.Convert(got.Palette[i]) != color.NRGBA(e.Color) {
+				t.Errorf("%s: color %d is %v, want %v", format, i, got.Palette[i], e.Color)
+			}
+		}
+		if !bytes.Equal(got.Pix, paletted.Pix) {
+			t.Errorf("%s: the indexes of the pixels changed", format)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package pixelforging
+
+import (
+	"math/rand"
+	"time"
+)
+
+const kMeansMaxIterationsDefault = 50
+
+// KMeansQuantizer clusters the pixels of the image in the CIELAB space and returns the
+// cluster centroids, so the palette colors are perceptually distinct from each other.
+// The initial centroids are chosen with k-means++.
+type KMeansQuantizer struct {
+	// Seed of the random initialization. The same seed always produces the same palette,
+	// 0 uses a different seed on every run.
+	Seed int64
+	// MaxIterations bounds the number of refinement steps, 0 uses the default (50).
+	MaxIterations int
+}
+
+// labSample is an unique color of the image in Lab space with its pixel count.
+type labSample struct {
+	lab   LabColor
+	alpha float64
+	count int
+}
+
+// Quantize implements Quantizer.
+func (q KMeansQuantizer) Quantize(histogram *Histogram, colorNum int) ([]ColorCount, error) {
+	if colorNum <= 0 {
+		return nil, checkColorNum(colorNum)
+	}
+	// Clustering the unique colors weighted by their count is the same as clustering
+	// every pixel, but much cheaper.
+	unique := histogram.Colors()
+
+	samples := make([]labSample, len(unique))
+	for i, c := range unique {
+		samples[i] = labSample{lab: RGBAToLab(c.Color), alpha: float64(c.Color.A), count: c.Count}
+	}
+	if len(samples) <= colorNum {
+		return unique, nil
+	}
+
+	seed := q.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	maxIterations := q.MaxIterations
+	if maxIterations <= 0 {
+		maxIterations = kMeansMaxIterationsDefault
+	}
+
+	centroids := kMeansPlusPlus(samples, colorNum, rand.New(rand.NewSource(seed)))
+	assignments := make([]int, len(samples))
+	for iteration := 0; iteration < maxIterations; iteration++ {
+		changed := iteration == 0
+		for i, s := range samples {
+			if nearest := nearestCentroid(s.lab, centroids); nearest != assignments[i] {
+				assignments[i] = nearest
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+		centroids = updateCentroids(samples, assignments, centroids)
+	}
+
+	// Accumulate the final clusters
+	counts := make([]int, len(centroids))
+	alphas := make([]float64, len(centroids))
+	for i, s := range samples {
+		counts[assignments[i]] += s.count
+		alphas[assignments[i]] += s.alpha * float64(s.count)
+	}
+
+	palette := make([]ColorCount, 0, len(centroids))
+	for i, centroid := range centroids {
+		if counts[i] == 0 {
+			continue
+		}
+		alpha := uint8(alphas[i]/float64(counts[i]) + 0.5)
+		palette = append(palette, ColorCount{Color: LabToRGBA(centroid, alpha), Count: counts[i]})
+	}
+	sortColorCounts(palette)
+	return palette, nil
+}
+
+// kMeansPlusPlus picks k initial centroids: the first one at random, and each next one
+// with a probability proportional to its squared distance to the closest centroid already chosen.
+func kMeansPlusPlus(samples []labSample, k int, rnd *rand.Rand) []LabColor {
+	centroids := make([]LabColor, 0, k)
+	distances := make([]float64, len(samples))
+
+	total := 0
+	for _, s := range samples {
+		total += s.count
+	}
+	target := rnd.Intn(total)
+	for _, s := range samples {
+		target -= s.count
+		if target < 0 {
+			centroids = append(centroids, s.lab)
+			break
+		}
+	}
+
+	for len(centroids) < k {
+		sum := 0.0
+		for i, s := range samples {
+			d := distanceLab(s.lab, centroids[len(centroids)-1])
+			if len(centroids) == 1 || d < distances[i] {
+				distances[i] = d
+			}
+			sum += distances[i] * float64(s.count)
+		}
+		if sum == 0 {
+			break
+		}
+
+		threshold := rnd.Float64() * sum
+		chosen := len(samples) - 1
+		for i, s := range samples {
+			threshold -= distances[i] * float64(s.count)
+			if threshold < 0 {
+				chosen = i
+				break
+			}
+		}
+		centroids = append(centroids, samples[chosen].lab)
+	}
+	return centroids
+}
+
+func nearestCentroid(lab LabColor, centroids []LabColor) int {
+	nearest, nearestDistance := 0, distanceLab(lab, centroids[0])
+	for i := 1; i < len(centroids); i++ {
+		if d := distanceLab(lab, centroids[i]); d < nearestDistance {
+			nearest, nearestDistance = i, d
+		}
+	}
+	return nearest
+}
+
+// updateCentroids moves every centroid to the weighted mean of its samples.
+// A centroid that lost all its samples keeps its position.
+func updateCentroids(samples []labSample, assignments []int, centroids []LabColor) []LabColor {
+	sums := make([]LabColor, len(centroids))
+	weights := make([]float64, len(centroids))
+	for i, s := range samples {
+		c := assignments[i]
+		w := float64(s.count)
+		sums[c].L += s.lab.L * w
+		sums[c].A += s.lab.A * w
+		sums[c].B += s.lab.B * w
+		weights[c] += w
+	}
+
+	updated := make([]LabColor, len(centroids))
+	for i := range centroids {
+		if weights[i] == 0 {
+			updated[i] = centroids[i]
+			continue
+		}
+		updated[i] = LabColor{L: sums[i].L / weights[i], A: sums[i].A / weights[i], B: sums[i].B / weights[i]}
+	}
+	return updated
+}
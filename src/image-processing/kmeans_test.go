@@ -0,0 +1,91 @@
+package pixelforging
+
+import (
+	"image/color"
+	"testing"
+)
+
+// clusteredColors returns three groups of colors around red, green and blue, each color
+// of a group a few levels apart.
+func clusteredColors() []ColorCount {
+	var colors []ColorCount
+	for i := range 5 {
+		v := uint8(i * 3)
+		colors = append(colors,
+			ColorCount{color.RGBA{R: 240 - v, G: v, B: v, A: 255}, 4},
+			ColorCount{color.RGBA{R: v, G: 200 - v, B: v, A: 255}, 3},
+			ColorCount{color.RGBA{R: v, G: v, B: 220 - v, A: 255}, 2},
+		)
+	}
+	return colors
+}
+
+func TestKMeansClusters(t *testing.T) {
+	histogram := newTestHistogram(t, clusteredColors()...)
+	palette, err := KMeansQuantizer{Seed: 1}.Quantize(histogram, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The clusters are sorted by their pixel count: the reds, the greens and the blues
+	wantCounts := []int{20, 15, 10}
+	if len(palette) != len(wantCounts) {
+		t.Fatalf("got %d colors, want %d", len(palette), len(wantCounts))
+	}
+	for i, c := range palette {
+		if c.Count != wantCounts[i] {
+			t.Errorf("cluster %d has %d pixels, want %d", i, c.Count, wantCounts[i])
+		}
+	}
+	if c := palette[0].Color; c.R < 200 || c.G > 20 || c.B > 20 {
+		t.Errorf("the red cluster is %v", c)
+	}
+	if c := palette[1].Color; c.G < 160 || c.R > 20 || c.B > 20 {
+		t.Errorf("the green cluster is %v", c)
+	}
+	if c := palette[2].Color; c.B < 180 || c.R > 20 || c.G > 20 {
+		t.Errorf("the blue cluster is %v", c)
+	}
+}
+
+func TestKMeansSeed(t *testing.T) {
+	histogram := newTestHistogram(t, clusteredColors()...)
+	tests := []struct {
+		seed     int64
+		colorNum int
+	}{
+		{1, 2},
+		{2, 4},
+		{42, 6},
+	}
+	for _, tt := range tests {
+		first, err := KMeansQuantizer{Seed: tt.seed}.Quantize(histogram, tt.colorNum)
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := KMeansQuantizer{Seed: tt.seed}.Quantize(histogram, tt.colorNum)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(first) != len(second) || len(first) > tt.colorNum {
+			t.Fatalf("seed %d: got %v and %v for %d colors", tt.seed, first, second, tt.colorNum)
+		}
+		for i := range first {
+			if first[i] != second[i] {
+				t.Errorf("seed %d: the same seed gave %v and %v", tt.seed, first, second)
+				break
+			}
+		}
+	}
+}
+
+func TestKMeansFewColors(t *testing.T) {
+	colors := []ColorCount{{color.RGBA{R: 255, A: 255}, 2}, {color.RGBA{B: 255, A: 255}, 1}}
+	palette, err := KMeansQuantizer{Seed: 1}.Quantize(newTestHistogram(t, colors...), 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The colors of the image are returned as they are
+	if len(palette) != len(colors) || palette[0] != colors[0] || palette[1] != colors[1] {
+		t.Errorf("got %v, want %v", palette, colors)
+	}
+}
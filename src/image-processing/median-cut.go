@@ -0,0 +1,136 @@
+package pixelforging
+
+import (
+	"image/color"
+	"sort"
+)
+
+// MedianCutQuantizer implements Heckbert's median-cut algorithm.
+// The color space is recursively split, at the median pixel of the widest channel,
+// into boxes holding a similar number of pixels. Each box becomes one palette color,
+// the average of the pixels inside it, so the palette follows the color distribution
+// of the image instead of its most frequent exact values.
+type MedianCutQuantizer struct{}
+
+// colorBox is a set of colors of the image with the number of pixels of each one.
+type colorBox struct {
+	colors []ColorCount
+	pixels int
+}
+
+// Quantize implements Quantizer.
+func (MedianCutQuantizer) Quantize(histogram *Histogram, colorNum int) ([]ColorCount, error) {
+	if colorNum <= 0 {
+		return nil, checkColorNum(colorNum)
+	}
+	box := colorBox{colors: histogram.Colors(), pixels: histogram.Total()}
+	if len(box.colors) == 0 {
+		return []ColorCount{}, nil
+	}
+
+	boxes := []colorBox{box}
+	for len(boxes) < colorNum {
+		i := widestBox(boxes)
+		if i < 0 {
+			break
+		}
+		left, right := boxes[i].split()
+		boxes[i] = left
+		boxes = append(boxes, right)
+	}
+
+	palette := make([]ColorCount, len(boxes))
+	for i, b := range boxes {
+		palette[i] = ColorCount{Color: b.average(), Count: b.pixels}
+	}
+	sortColorCounts(palette)
+	return palette, nil
+}
+
+// widestBox returns the index of the splittable box with the largest channel range,
+// or -1 if every box holds a single color.
+func widestBox(boxes []colorBox) int {
+	best, bestRange := -1, -1
+	for i, b := range boxes {
+		if len(b.colors) < 2 {
+			continue
+		}
+		if _, r := b.widestChannel(); r > bestRange {
+			best, bestRange = i, r
+		}
+	}
+	return best
+}
+
+// widestChannel returns the RGBA channel (0..3) with the largest range in the box and that range.
+func (b colorBox) widestChannel() (int, int) {
+	minC := [4]int{255, 255, 255, 255}
+	maxC := [4]int{}
+	for _, c := range b.colors {
+		for ch, v := range channels(c.Color) {
+			minC[ch] = min(minC[ch], v)
+			maxC[ch] = max(maxC[ch], v)
+		}
+	}
+	channel, channelRange := 0, -1
+	for ch := range minC {
+		if r := maxC[ch] - minC[ch]; r > channelRange {
+			channel, channelRange = ch, r
+		}
+	}
+	return channel, channelRange
+}
+
+// split divides the box in two at the weighted median of its widest channel.
+func (b colorBox) split() (colorBox, colorBox) {
+	channel, _ := b.widestChannel()
+	sort.Slice(b.colors, func(i, j int) bool {
+		ci, cj := channelOf(b.colors[i].Color, channel), channelOf(b.colors[j].Color, channel)
+		if ci != cj {
+			return ci < cj
+		}
+		return colorKey(b.colors[i].Color) < colorKey(b.colors[j].Color)
+	})
+
+	// Both halves must keep at least one color
+	cut, acc := 1, b.colors[0].Count
+	for cut < len(b.colors)-1 && acc+b.colors[cut].Count <= b.pixels/2 {
+		acc += b.colors[cut].Count
+		cut++
+	}
+
+	left := colorBox{colors: b.colors[:cut], pixels: acc}
+	right := colorBox{colors: b.colors[cut:], pixels: b.pixels - acc}
+	return left, right
+}
+
+// average returns the mean color of the box weighted by the pixel count of each color.
+func (b colorBox) average() color.RGBA {
+	var sum [4]int
+	for _, c := range b.colors {
+		for ch, v := range channels(c.Color) {
+			sum[ch] += v * c.Count
+		}
+	}
+	avg := func(ch int) uint8 {
+		return uint8((sum[ch] + b.pixels/2) / b.pixels)
+	}
+	return color.RGBA{R: avg(0), G: avg(1), B: avg(2), A: avg(3)}
+}
+
+func channelOf(c color.RGBA, channel int) uint8 {
+	switch channel {
+	case 0:
+		return c.R
+	case 1:
+		return c.G
+	case 2:
+		return c.B
+	default:
+		return c.A
+	}
+}
+
+func channels(c color.RGBA) [4]int {
+	return [4]int{int(c.R), int(c.G), int(c.B), int(c.A)}
+}
@@ -0,0 +1,61 @@
+package pixelforging
+
+import (
+	"image/color"
+	"testing"
+)
+
+// spreadColors returns 256 colors spread over the RGB cube, of one pixel each.
+func spreadColors() []ColorCount {
+	colors := make([]ColorCount, 256)
+	for i := range colors {
+		colors[i] = ColorCount{color.RGBA{R: uint8(i), G: uint8(i * 7), B: uint8(i * 13), A: 255}, 1}
+	}
+	return colors
+}
+
+func TestMedianCutColorCount(t *testing.T) {
+	histogram := newTestHistogram(t, spreadColors()...)
+	for _, colorNum := range []int{1, 2, 6, 16, 100, 256, 300} {
+		palette, err := MedianCutQuantizer{}.Quantize(histogram, colorNum)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := min(colorNum, 256); len(palette) != want {
+			t.Errorf("%d colors: got %d colors, want %d", colorNum, len(palette), want)
+		}
+		total := 0
+		for _, c := range palette {
+			total += c.Count
+		}
+		if total != histogram.Total() {
+			t.Errorf("%d colors: the boxes hold %d pixels, want %d", colorNum, total, histogram.Total())
+		}
+	}
+}
+
+func TestMedianCutAverages(t *testing.T) {
+	// Two groups of reds and blues, split at the median of the widest channel
+	histogram := newTestHistogram(t,
+		ColorCount{color.RGBA{R: 250, A: 255}, 3},
+		ColorCount{color.RGBA{R: 200, A: 255}, 1},
+		ColorCount{color.RGBA{B: 100, A: 255}, 2},
+		ColorCount{color.RGBA{B: 40, A: 255}, 2},
+	)
+	got, err := MedianCutQuantizer{}.Quantize(histogram, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The average is weighted by the pixel counts: (3*250+200)/4 rounds to 238. Boxes of the
+	// same count are sorted by color value
+	want := []ColorCount{{color.RGBA{B: 70, A: 255}, 4}, {color.RGBA{R: 238, A: 255}, 4}}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %v, want %v", got, want)
+			break
+		}
+	}
+}
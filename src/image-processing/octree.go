@@ -0,0 +1,226 @@
+package pixelforging
+
+import (
+	"image/color"
+)
+
+const (
+	octreeMaxDepth = 8
+	// octreeLeavesBudget bounds the number of leaves kept while the colors are inserted,
+	// so the memory used by the tree does not grow with the number of colors.
+	octreeLeavesBudget = 2048
+)
+
+// OctreeQuantizer builds an octree of the image colors, where each level splits the RGB
+// cube in 8 by one more bit of each channel, and prunes it until only colorNum leaves are left.
+// The tree is pruned while it is built, so its memory is bounded no matter how many colors
+// the histogram has.
+type OctreeQuantizer struct {
+	// Depth of the tree, from 1 to 8. A smaller depth groups similar colors earlier and
+	// uses less memory, 0 uses the maximum depth (8).
+	Depth int
+}
+
+type octreeNode struct {
+	r, g, b, a uint64
+	pixels     int
+	children   [8]*octreeNode
+	leaf       bool
+}
+
+type octree struct {
+	depth  int
+	root   *octreeNode
+	leaves int
+	// reducible holds the inner nodes of each level, the candidates to be pruned
+	reducible [octreeMaxDepth][]*octreeNode
+}
+
+// Quantize implements Quantizer.
+func (q OctreeQuantizer) Quantize(histogram *Histogram, colorNum int) ([]ColorCount, error) {
+	if colorNum <= 0 {
+		return nil, checkColorNum(colorNum)
+	}
+	depth := q.Depth
+	if depth <= 0 || depth > octreeMaxDepth {
+		depth = octreeMaxDepth
+	}
+	tree := &octree{depth: depth, root: &octreeNode{}}
+	budget := max(colorNum, octreeLeavesBudget)
+
+	for _, c := range histogram.Colors() {
+		tree.insert(c.Color, c.Count)
+		tree.pruneTo(budget)
+	}
+	tree.pruneTo(colorNum)
+
+	palette := make([]ColorCount, 0, tree.leaves)
+	tree.root.collect(&palette)
+	sortColorCounts(palette)
+	return palette, nil
+}
+
+// insert adds n pixels of the color to the tree.
+func (t *octree) insert(c color.RGBA, n int) {
+	node := t.root
+	for level := 0; level < t.depth; level++ {
+		if node.leaf {
+			break
+		}
+		i := octreeIndex(c, level)
+		if node.children[i] == nil {
+			child := &octreeNode{}
+			if level == t.depth-1 {
+				child.leaf = true
+				t.leaves++
+			} else {
+				t.reducible[level+1] = append(t.reducible[level+1], child)
+			}
+			node.children[i] = child
+		}
+		node = node.children[i]
+	}
+	node.r += uint64(c.R) * uint64(n)
+	node.g += uint64(c.G) * uint64(n)
+	node.b += uint64(c.B) * uint64(n)
+	node.a += uint64(c.A) * uint64(n)
+	node.pixels += n
+}
+
+// pruneTo reduces the tree until it has at most maxLeaves leaves. The tree is never reduced
+// below maxLeaves leaves, so a palette of colorNum colors has exactly colorNum colors when the
+// image has that many leaves.
+func (t *octree) pruneTo(maxLeaves int) {
+	for t.leaves > maxLeaves {
+		if !t.reduce(t.leaves - maxLeaves) {
+			return
+		}
+	}
+}
+
+// reduce merges the children of the deepest inner node that holds the fewest pixels into it,
+// removing at most excess leaves. When merging all the children would remove more leaves than
+// that, only the smallest children are merged with each other and the node is kept.
+// It returns false when the whole tree was already merged into a single color.
+func (t *octree) reduce(excess int) bool {
+	for level := t.depth - 1; level >= 0; level-- {
+		nodes := t.reducible[level]
+		if level == 0 {
+			if t.root.leaf {
+				return false
+			}
+			nodes = []*octreeNode{t.root}
+		}
+		if len(nodes) == 0 {
+			continue
+		}
+
+		best, bestPixels := 0, -1
+		for i, n := range nodes {
+			if p := n.subtreePixels(); bestPixels < 0 || p < bestPixels {
+				best, bestPixels = i, p
+			}
+		}
+		node := nodes[best]
+		if children := node.childCount(); children-1 > excess {
+			for range excess {
+				node.mergeSmallestChildren()
+				t.leaves--
+			}
+			return true
+		}
+		if level > 0 {
+			t.reducible[level] = append(nodes[:best], nodes[best+1:]...)
+		}
+
+		for i, child := range node.children {
+			if child == nil {
+				continue
+			}
+			// Only leaves are left below the deepest inner level
+			node.r += child.r
+			node.g += child.g
+			node.b += child.b
+			node.a += child.a
+			node.pixels += child.pixels
+			node.children[i] = nil
+			t.leaves--
+		}
+		node.leaf = true
+		t.leaves++
+		return true
+	}
+	return false
+}
+
+// childCount returns the number of children of the node.
+func (n *octreeNode) childCount() int {
+	count := 0
+	for _, child := range n.children {
+		if child != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// mergeSmallestChildren merges the leaf child of the node that holds the fewest pixels into
+// the next smallest one. The node must have at least 2 children, all of them leaves.
+func (n *octreeNode) mergeSmallestChildren() {
+	smallest, next := -1, -1
+	for i, child := range n.children {
+		switch {
+		case child == nil:
+		case smallest < 0 || child.pixels < n.children[smallest].pixels:
+			smallest, next = i, smallest
+		case next < 0 || child.pixels < n.children[next].pixels:
+			next = i
+		}
+	}
+	from, to := n.children[smallest], n.children[next]
+	to.r += from.r
+	to.g += from.g
+	to.b += from.b
+	to.a += from.a
+	to.pixels += from.pixels
+	n.children[smallest] = nil
+}
+
+func (n *octreeNode) subtreePixels() int {
+	if n.leaf {
+		return n.pixels
+	}
+	total := n.pixels
+	for _, child := range n.children {
+		if child != nil {
+			total += child.subtreePixels()
+		}
+	}
+	return total
+}
+
+func (n *octreeNode) collect(palette *[]ColorCount) {
+	if n.leaf {
+		if n.pixels > 0 {
+			avg := func(sum uint64) uint8 {
+				return uint8((sum + uint64(n.pixels)/2) / uint64(n.pixels))
+			}
+			*palette = append(*palette, ColorCount{
+				Color: color.RGBA{R: avg(n.r), G: avg(n.g), B: avg(n.b), A: avg(n.a)},
+				Count: n.pixels,
+			})
+		}
+		return
+	}
+	for _, child := range n.children {
+		if child != nil {
+			child.collect(palette)
+		}
+	}
+}
+
+// octreeIndex returns which of the 8 children holds the color at the given level.
+func octreeIndex(c color.RGBA, level int) int {
+	shift := 7 - level
+	return int((c.R>>shift)&1)<<2 | int((c.G>>shift)&1)<<1 | int((c.B>>shift)&1)
+}
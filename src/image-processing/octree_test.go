@@ -0,0 +1,63 @@
+package pixelforging
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestOctreeColorCount(t *testing.T) {
+	histogram := newTestHistogram(t, spreadColors()...)
+	tests := []struct {
+		depth    int
+		colorNum int
+		want     int
+	}{
+		{0, 1, 1},
+		{0, 6, 6},
+		{0, 16, 16},
+		{0, 100, 100},
+		{0, 256, 256},
+		{4, 32, 32},
+		// A tree of depth 1 has at most 8 leaves
+		{1, 16, 8},
+	}
+	for _, tt := range tests {
+		palette, err := OctreeQuantizer{Depth: tt.depth}.Quantize(histogram, tt.colorNum)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(palette) != tt.want {
+			t.Errorf("depth %d, %d colors: got %d colors, want %d", tt.depth, tt.colorNum, len(palette), tt.want)
+		}
+		total := 0
+		for _, c := range palette {
+			total += c.Count
+		}
+		if total != histogram.Total() {
+			t.Errorf("depth %d, %d colors: the leaves hold %d pixels, want %d", tt.depth, tt.colorNum, total, histogram.Total())
+		}
+	}
+}
+
+func TestOctreeLeafAverage(t *testing.T) {
+	// Both reds fall in the same octant of the first level, the blue in another one
+	histogram := newTestHistogram(t,
+		ColorCount{color.RGBA{R: 200, A: 255}, 1},
+		ColorCount{color.RGBA{R: 240, G: 20, A: 255}, 3},
+		ColorCount{color.RGBA{B: 200, A: 255}, 2},
+	)
+	got, err := OctreeQuantizer{Depth: 1}.Quantize(histogram, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ColorCount{{color.RGBA{R: 230, G: 15, A: 255}, 4}, {color.RGBA{B: 200, A: 255}, 2}}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %v, want %v", got, want)
+			break
+		}
+	}
+}
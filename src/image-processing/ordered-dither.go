@@ -0,0 +1,265 @@
+package pixelforging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Ordered dithering methods. They add to every pixel an offset read from a threshold map
+// tiled over the image, so the result tiles cleanly and does not shimmer in animations.
+const (
+	DitherBayer2    DitherMethod = "bayer-2x2"
+	DitherBayer4    DitherMethod = "bayer-4x4"
+	DitherBayer8    DitherMethod = "bayer-8x8"
+	DitherBlueNoise DitherMethod = "blue-noise"
+	// DitherCustomMatrix uses the threshold map of DitherOptions.Matrix.
+	DitherCustomMatrix DitherMethod = "custom"
+)
+
+// blueNoiseSize is the side of the blue-noise threshold map.
+const blueNoiseSize = 32
+
+var (
+	blueNoiseOnce   sync.Once
+	blueNoiseMatrix [][]float64
+)
+
+// isOrderedDither tells if the method is an ordered dithering method.
+func isOrderedDither(method DitherMethod) bool {
+	switch method {
+	case DitherBayer2, DitherBayer4, DitherBayer8, DitherBlueNoise, DitherCustomMatrix:
+		return true
+	}
+	return false
+}
+
+// ParseDitherMatrix reads a threshold map written as rows separated by ";" and values
+// separated by ",", like "0,2;3,1".
+func ParseDitherMatrix(s string) ([][]float64, error) {
+	var matrix [][]float64
+	for _, line := range strings.Split(s, ";") {
+		var row []float64
+		for _, field := range strings.Split(line, ",") {
+			v, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid dithering matrix value %q", ErrInvalidOption, field)
+			}
+			row = append(row, v)
+		}
+		matrix = append(matrix, row)
+	}
+	return matrix, validateDitherMatrix(matrix)
+}
+
+// validateDitherMatrix checks that the matrix is a non empty rectangle of values >= 0.
+func validateDitherMatrix(matrix [][]float64) error {
+	if len(matrix) == 0 || len(matrix[0]) == 0 {
+		return fmt.Errorf("%w: the dithering matrix is empty", ErrInvalidOption)
+	}
+	for _, row := range matrix {
+		if len(row) != len(matrix[0]) {
+			return fmt.Errorf("%w: every row of the dithering matrix should have the same size", ErrInvalidDimensions)
+		}
+		for _, v := range row {
+			if v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+				return fmt.Errorf("%w: the dithering matrix values should be numbers >= 0, got %v", ErrInvalidOption, v)
+			}
+		}
+	}
+	return nil
+}
+
+// thresholdMap returns the matrix used by an ordered dithering method.
+func thresholdMap(method DitherMethod, custom [][]float64) ([][]float64, error) {
+	switch method {
+	case DitherBayer2:
+		return bayerMatrix(2), nil
+	case DitherBayer4:
+		return bayerMatrix(4), nil
+	case DitherBayer8:
+		return bayerMatrix(8), nil
+	case DitherBlueNoise:
+		blueNoiseOnce.Do(func() { blueNoiseMatrix = voidAndCluster(blueNoiseSize, 1.5) })
+		return blueNoiseMatrix, nil
+	default:
+		return custom, validateDitherMatrix(custom)
+	}
+}
+
+// bayerMatrix builds the size x size Bayer matrix, size being a power of 2, from the
+// 2x2 one: every cell v of the matrix of half the size becomes the 2x2 block
+// 4v, 4v+2 / 4v+3, 4v+1.
+func bayerMatrix(size int) [][]float64 {
+	matrix := [][]float64{{0}}
+	for n := 1; n < size; n *= 2 {
+		next := make([][]float64, 2*n)
+		for y := range next {
+			next[y] = make([]float64, 2*n)
+		}
+		for y := 0; y < n; y++ {
+			for x := 0; x < n; x++ {
+				v := 4 * matrix[y][x]
+				next[y][x] = v
+				next[y][x+n] = v + 2
+				next[y+n][x] = v + 3
+				next[y+n][x+n] = v + 1
+			}
+		}
+		matrix = next
+	}
+	return matrix
+}
+
+// voidAndCluster builds a size x size blue-noise threshold map with the void-and-cluster
+// algorithm of Ulichney: the pixels are ranked by repeatedly taking the tightest cluster
+// or the largest void of a binary pattern, measured by a gaussian filter that wraps
+// around the edges. The map is the same on every run.
+func voidAndCluster(size int, sigma float64) [][]float64 {
+	n := size * size
+	// gaussian[dy*size+dx] is the weight of a pixel at the toroidal offset (dx, dy)
+	gaussian := make([]float64, n)
+	for dy := 0; dy < size; dy++ {
+		for dx := 0; dx < size; dx++ {
+			wx, wy := float64(min(dx, size-dx)), float64(min(dy, size-dy))
+			gaussian[dy*size+dx] = math.Exp(-(wx*wx + wy*wy) / (2 * sigma * sigma))
+		}
+	}
+
+	pattern := make([]bool, n)
+	energy := make([]float64, n)
+	toggle := func(p int, set bool) {
+		pattern[p] = set
+		sign := 1.0
+		if !set {
+			sign = -1
+		}
+		px, py := p%size, p/size
+		for q := range energy {
+			dx := (q%size - px + size) % size
+			dy := (q/size - py + size) % size
+			energy[q] += sign * gaussian[dy*size+dx]
+		}
+	}
+	// tightestCluster is the set pixel with the most energy, largestVoid the unset one with the least
+	tightestCluster := func() int {
+		best := -1
+		for p, set := range pattern {
+			if set && (best < 0 || energy[p] > energy[best]) {
+				best = p
+			}
+		}
+		return best
+	}
+	largestVoid := func() int {
+		best := -1
+		for p, set := range pattern {
+			if !set && (best < 0 || energy[p] < energy[best]) {
+				best = p
+			}
+		}
+		return best
+	}
+
+	// Initial pattern: a tenth of the pixels at random, spread until the tightest
+	// cluster is also the largest void
+	rnd := rand.New(rand.NewSource(1))
+	ones := n / 10
+	for _, p := range rnd.Perm(n)[:ones] {
+		toggle(p, true)
+	}
+	for {
+		cluster := tightestCluster()
+		toggle(cluster, false)
+		void := largestVoid()
+		toggle(void, true)
+		if void == cluster {
+			break
+		}
+	}
+	initial := append([]bool(nil), pattern...)
+	initialEnergy := append([]float64(nil), energy...)
+
+	ranks := make([]float64, n)
+	// Phase 1: the initial pixels are ranked from the tightest cluster down
+	for rank := ones - 1; rank >= 0; rank-- {
+		p := tightestCluster()
+		toggle(p, false)
+		ranks[p] = float64(rank)
+	}
+	// Phase 2: the remaining pixels are ranked filling the largest void. Once half of the
+	// pixels are set, the largest void of the set pixels is the tightest cluster of the
+	// unset ones, so the same step also covers the last phase of the algorithm
+	copy(pattern, initial)
+	copy(energy, initialEnergy)
+	for rank := ones; rank < n; rank++ {
+		p := largestVoid()
+		toggle(p, true)
+		ranks[p] = float64(rank)
+	}
+
+	matrix := make([][]float64, size)
+	for y := range matrix {
+		matrix[y] = ranks[y*size : (y+1)*size]
+	}
+	return matrix
+}
+
+// orderedDither maps the pixels to the colors after adding to each one an offset read
+// from the threshold map. The offsets go from -spread/2 to spread/2, where spread is the
+// typical distance between neighbour colors of the palette scaled by the strength.
+func orderedDither(img image.Image, colors []color.RGBA, metric DistanceMetric, matrix [][]float64, strength float64) *image.NRGBA {
+	height, width := len(matrix), len(matrix[0])
+	levels := 0.0
+	for _, row := range matrix {
+		for _, v := range row {
+			levels = math.Max(levels, v+1)
+		}
+	}
+	spread := strength * paletteSpread(colors)
+	offsets := make([][]float64, height)
+	for y, row := range matrix {
+		offsets[y] = make([]float64, width)
+		for x, v := range row {
+			offsets[y][x] = ((v+0.5)/levels - 0.5) * spread
+		}
+	}
+
+	bounds := img.Bounds()
+	return mapToColors(img, colors, metric, func(x, y int, c color.RGBA) color.RGBA {
+		offset := offsets[(y-bounds.Min.Y)%height][(x-bounds.Min.X)%width]
+		return color.RGBA{
+			to8Bit((float64(c.R) + offset) / 255),
+			to8Bit((float64(c.G) + offset) / 255),
+			to8Bit((float64(c.B) + offset) / 255),
+			c.A,
+		}
+	})
+}
+
+// paletteSpread is the mean distance, in the channel that differs the most, between each
+// color and the nearest other color of the palette.
+func paletteSpread(colors []color.RGBA) float64 {
+	if len(colors) < 2 {
+		return 0
+	}
+	total := 0.0
+	for i, a := range colors {
+		nearest := math.Inf(1)
+		for j, b := range colors {
+			if i == j {
+				continue
+			}
+			d := math.Max(math.Abs(float64(a.R)-float64(b.R)), math.Max(
+				math.Abs(float64(a.G)-float64(b.G)), math.Abs(float64(a.B)-float64(b.B))))
+			nearest = math.Min(nearest, d)
+		}
+		total += nearest
+	}
+	return total / float64(len(colors))
+}
@@ -0,0 +1,172 @@
+package pixelforging
+
+import (
+	"errors"
+	"image/color"
+	"slices"
+	"testing"
+)
+
+func TestBayerMatrix(t *testing.T) {
+	tests := []struct {
+		size int
+		want [][]float64
+	}{
+		{2, [][]float64{{0, 2}, {3, 1}}},
+		{4, [][]float64{
+			{0, 8, 2, 10},
+			{12, 4, 14, 6},
+			{3, 11, 1, 9},
+			{15, 7, 13, 5},
+		}},
+	}
+	for _, tt := range tests {
+		got := bayerMatrix(tt.size)
+		for y := range tt.want {
+			if !slices.Equal(got[y], tt.want[y]) {
+				t.Errorf("size %d: row %d is %v, want %v", tt.size, y, got[y], tt.want[y])
+			}
+		}
+	}
+}
+
+// isRanking tells if the matrix holds every rank from 0 to its number of cells once.
+func isRanking(matrix [][]float64) bool {
+	var values []float64
+	for _, row := range matrix {
+		values = append(values, row...)
+	}
+	slices.Sort(values)
+	for i, v := range values {
+		if v != float64(i) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestThresholdMaps(t *testing.T) {
+	tests := []struct {
+		method DitherMethod
+		size   int
+	}{
+		{DitherBayer2, 2},
+		{DitherBayer4, 4},
+		{DitherBayer8, 8},
+		{DitherBlueNoise, blueNoiseSize},
+	}
+	for _, tt := range tests {
+		matrix, err := thresholdMap(tt.method, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.method, err)
+		}
+		if len(matrix) != tt.size || len(matrix[0]) != tt.size {
+			t.Errorf("%s: got a %dx%d matrix, want %dx%d", tt.method, len(matrix[0]), len(matrix), tt.size, tt.size)
+		}
+		if !isRanking(matrix) {
+			t.Errorf("%s: the matrix does not rank every cell once", tt.method)
+		}
+	}
+
+	if _, err := thresholdMap(DitherCustomMatrix, nil); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("custom without a matrix: got %v, want ErrInvalidOption", err)
+	}
+}
+
+func TestParseDitherMatrix(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    [][]float64
+		wantErr error
+	}{
+		{"0,2;3,1", [][]float64{{0, 2}, {3, 1}}, nil},
+		{" 0 , 1.5 ", [][]float64{{0, 1.5}}, nil},
+		{"", nil, ErrInvalidOption},
+		{"0,x", nil, ErrInvalidOption},
+		{"0,-1", nil, ErrInvalidOption},
+		{"0,NaN", nil, ErrInvalidOption},
+		{"0,1;2", nil, ErrInvalidDimensions},
+	}
+	for _, tt := range tests {
+		got, err := ParseDitherMatrix(tt.s)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%q: got %v, want %v", tt.s, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr != nil {
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Fatalf("%q: got %v, want %v", tt.s, got, tt.want)
+		}
+		for y := range tt.want {
+			if !slices.Equal(got[y], tt.want[y]) {
+				t.Errorf("%q: row %d is %v, want %v", tt.s, y, got[y], tt.want[y])
+			}
+		}
+	}
+}
+
+func TestPaletteSpread(t *testing.T) {
+	tests := []struct {
+		colors []color.RGBA
+		want   float64
+	}{
+		{nil, 0},
+		{[]color.RGBA{{R: 10, A: 255}}, 0},
+		{blackAndWhite().Colors(), 255},
+		// The distance is the channel that differs the most: black and the green are 10 apart,
+		// the blue is 40 apart from both
+		{[]color.RGBA{{A: 255}, {G: 10, A: 255}, {B: 40, A: 255}}, 20},
+	}
+	for _, tt := range tests {
+		if got := paletteSpread(tt.colors); got != tt.want {
+			t.Errorf("%v: got %v, want %v", tt.colors, got, tt.want)
+		}
+	}
+}
+
+func TestOrderedDitherTilesTheMatrix(t *testing.T) {
+	const size = 16
+	img := grayImage(size, size, 128)
+	for _, method := range []DitherMethod{DitherBayer2, DitherBayer4, DitherBayer8} {
+		out, err := DitherImage(img, blackAndWhite(), MetricRGB, DitherOptions{Method: method})
+		if err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+		matrix, _ := thresholdMap(method, nil)
+		period := len(matrix)
+		white := 0
+		for y := range size {
+			for x := range size {
+				c := out.NRGBAAt(x, y)
+				if c.R == 255 {
+					white++
+				}
+				// The upper half of the thresholds turns the middle gray white
+				if wantWhite := matrix[y%period][x%period] >= float64(period*period/2); (c.R == 255) != wantWhite {
+					t.Errorf("%s: pixel (%d, %d) is %v", method, x, y, c)
+				}
+			}
+		}
+		if white != size*size/2 {
+			t.Errorf("%s: %d pixels are white, want %d", method, white, size*size/2)
+		}
+	}
+}
+
+func TestDitherImageCustomMatrix(t *testing.T) {
+	img := randomImage(10, 10, 9)
+	palette := testPalette()
+	bayer, err := DitherImage(img, palette, MetricRGB, DitherOptions{Method: DitherBayer2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	custom, err := DitherImage(img, palette, MetricRGB, DitherOptions{Method: DitherCustomMatrix, Matrix: bayerMatrix(2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bayer.Pix) != string(custom.Pix) {
+		t.Errorf("the custom 2x2 Bayer matrix gave a different image than %s", DitherBayer2)
+	}
+}
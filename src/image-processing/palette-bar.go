@@ -0,0 +1,75 @@
+package pixelforging
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// barWidths splits the width of the bar between the entries of the palette, proportionally
+// to the pixels each one represents. The pixels left by the rounding go to the entries with
+// the largest remainders, and every visible entry keeps at least 1 pixel. Palettes without
+// pixel counts, like the ones read from files, are split in equal parts.
+func barWidths(palette Palette, width int) []int {
+	shares := make([]int, len(palette.Entries))
+	total := 0
+	for i, e := range palette.Entries {
+		switch {
+		case e.Color.A == 0:
+		case palette.TotalPixels > 0:
+			shares[i] = e.Count
+		default:
+			shares[i] = 1
+		}
+		total += shares[i]
+	}
+
+	widths := make([]int, len(shares))
+	if total == 0 {
+		return widths
+	}
+	remainders := make([]int, len(shares))
+	used := 0
+	for i, share := range shares {
+		widths[i] = share * width / total
+		remainders[i] = share * width % total
+		used += widths[i]
+	}
+	for ; used < width; used++ {
+		largest := 0
+		for i := range remainders {
+			if remainders[i] > remainders[largest] {
+				largest = i
+			}
+		}
+		widths[largest]++
+		remainders[largest] = -1
+	}
+	for i := range widths {
+		if shares[i] > 0 && widths[i] == 0 {
+			widths[i] = 1
+		}
+	}
+	return widths
+}
+
+// createColorBar draws the entries of the palette side by side, each one as wide as set by
+// widths. When labels is set, the entries wide enough for their label have it written at the
+// bottom.
+func createColorBar(palette Palette, widths []int, colorHeight int, labels bool) (image.Image, error) {
+	labelWidth, _ := labelSize(palette)
+	colorBlocks := make([]image.Image, 0, len(widths))
+	for i, width := range widths {
+		if width == 0 {
+			continue
+		}
+		c := palette.Entries[i].Color
+		img := image.NewRGBA(image.Rect(0, 0, width, colorHeight))
+		draw.Draw(img, img.Bounds(), &image.Uniform{color.NRGBA(c)}, image.Point{}, draw.Src)
+		if labels && width >= labelWidth {
+			drawLabel(img, c, labelLines(palette, i))
+		}
+		colorBlocks = append(colorBlocks, img)
+	}
+	return concatenateImagesHorizontal(colorHeight, colorBlocks...)
+}
@@ -0,0 +1,67 @@
+package pixelforging
+
+import (
+	"image/color"
+	"slices"
+	"testing"
+)
+
+func TestBarWidths(t *testing.T) {
+	opaque := color.RGBA{R: 90, A: 255}
+	tests := []struct {
+		name        string
+		counts      []int
+		transparent []int
+		total       int
+		width       int
+		want        []int
+	}{
+		{"proportional", []int{50, 30, 20}, nil, 100, 200, []int{100, 60, 40}},
+		// 171, 85 and 42 pixels leave the remainders 3/7, 5/7 and 6/7, the 2 pixels left go
+		// to the last two
+		{"largest remainders", []int{4, 2, 1}, nil, 7, 300, []int{171, 86, 43}},
+		{"at least 1 pixel", []int{998, 1, 1}, nil, 1000, 100, []int{100, 1, 1}},
+		{"transparent gets no width", []int{3, 1, 0}, []int{2}, 4, 40, []int{30, 10, 0}},
+		{"no pixel counts", []int{0, 0, 0, 0}, nil, 0, 10, []int{3, 3, 2, 2}},
+		{"only transparent", []int{0}, []int{0}, 0, 10, []int{0}},
+	}
+	for _, tt := range tests {
+		palette := Palette{TotalPixels: tt.total}
+		for i, n := range tt.counts {
+			c := opaque
+			if slices.Contains(tt.transparent, i) {
+				c = color.RGBA{}
+			}
+			palette.Entries = append(palette.Entries, NewPaletteEntry(c, n, tt.total))
+		}
+		if got := barWidths(palette, tt.width); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRenderPaletteBar(t *testing.T) {
+	red, blue := color.RGBA{R: 255, A: 255}, color.RGBA{B: 255, A: 255}
+	palette := Palette{TotalPixels: 4, Entries: []PaletteEntry{
+		NewPaletteEntry(red, 3, 4),
+		NewPaletteEntry(color.RGBA{}, 0, 4),
+		NewPaletteEntry(blue, 1, 4),
+	}}
+	img, err := RenderPalette(palette, PaletteLayout{Mode: LayoutBar, ColorWidth: 4, ColorHeight: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The bar is as wide as the 3 colors in a row of the grid, 12 pixels, split 9 and 3
+	if b := img.Bounds(); b.Dx() != 12 || b.Dy() != 5 {
+		t.Fatalf("got a %dx%d image, want 12x5", b.Dx(), b.Dy())
+	}
+	for x := range 12 {
+		want := red
+		if x >= 9 {
+			want = blue
+		}
+		if got := rgbaAt(img, x, 2); got != want {
+			t.Errorf("pixel %d is %v, want %v", x, got, want)
+		}
+	}
+}
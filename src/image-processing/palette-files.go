@@ -0,0 +1,410 @@
+package pixelforging
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// PaletteFormat is a file format a palette can be saved as or read from.
+type PaletteFormat string
+
+// Supported palette file formats.
+const (
+	// PaletteFormatPNG is the swatch grid image drawn by RenderPalette.
+	PaletteFormatPNG PaletteFormat = "png"
+	// PaletteFormatGPL is the GIMP palette (.gpl), also read by Aseprite and Inkscape.
+	PaletteFormatGPL PaletteFormat = "gpl"
+	// PaletteFormatPaintNET is the Paint.NET palette (.txt).
+	PaletteFormatPaintNET PaletteFormat = "paintnet"
+	// PaletteFormatJASC is the JASC-PAL palette (.pal) of Paint Shop Pro, also read by Aseprite.
+	PaletteFormatJASC PaletteFormat = "jasc"
+	// PaletteFormatHex is a plain list of "rrggbb" hex codes (.hex), as used by Lospec.
+	PaletteFormatHex PaletteFormat = "hex"
+	// PaletteFormatASE is the Adobe Swatch Exchange file (.ase), shared by all Adobe tools.
+	PaletteFormatASE PaletteFormat = "ase"
+	// PaletteFormatACO is the Photoshop Color Swatch file (.aco).
+	PaletteFormatACO PaletteFormat = "aco"
+	// PaletteFormatSVG is the swatch sheet as an SVG image (.svg), see EncodeSwatchSheet.
+	PaletteFormatSVG PaletteFormat = "svg"
+	// PaletteFormatHTML is the swatch sheet as an HTML page (.html), see EncodeSwatchSheet.
+	PaletteFormatHTML PaletteFormat = "html"
+)
+
+// paintNETMaxColors is the number of colors a Paint.NET palette holds.
+const paintNETMaxColors = 96
+
+// maxPaletteImageColors is the most colors an image read as a palette can have. Images
+// with more colors are pictures, not swatch grids.
+const maxPaletteImageColors = 1024
+
+var paletteFormatExtensions = map[string]PaletteFormat{
+	".png":  PaletteFormatPNG,
+	".gpl":  PaletteFormatGPL,
+	".txt":  PaletteFormatPaintNET,
+	".pal":  PaletteFormatJASC,
+	".hex":  PaletteFormatHex,
+	".ase":  PaletteFormatASE,
+	".aco":  PaletteFormatACO,
+	".svg":  PaletteFormatSVG,
+	".html": PaletteFormatHTML,
+	".htm":  PaletteFormatHTML,
+}
+
+// ParsePaletteFormat validates a palette format name.
+func ParsePaletteFormat(name string) (PaletteFormat, error) {
+	format := PaletteFormat(strings.ToLower(name))
+	for _, known := range paletteFormatExtensions {
+		if format == known {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("%w: unknown palette format %q", ErrUnsupportedFormat, name)
+}
+
+// PaletteFormatFromPath infers the palette format from the extension of the file path.
+// Unknown extensions use PNG, the format used before the others were supported.
+func PaletteFormatFromPath(path string) PaletteFormat {
+	if format, ok := paletteFormatExtensions[strings.ToLower(filepath.Ext(path))]; ok {
+		return format
+	}
+	return PaletteFormatPNG
+}
+
+// EncodePalette writes the palette to w in a palette file format. The name is stored
+// in the formats that have a palette name. The swatch sheets, PNG, SVG and HTML, must be
+// drawn with EncodeSwatchSheet.
+// The writes to w are buffered, so write errors are only reported when the buffer is flushed.
+func EncodePalette(w io.Writer, palette Palette, format PaletteFormat, name string) error {
+	buf := bufio.NewWriter(w)
+	switch format {
+	case PaletteFormatGPL:
+		encodeGPL(buf, palette, name)
+	case PaletteFormatPaintNET:
+		encodePaintNET(buf, palette, name)
+	case PaletteFormatJASC:
+		encodeJASC(buf, palette)
+	case PaletteFormatHex:
+		encodeHex(buf, palette)
+	case PaletteFormatASE:
+		encodeASE(buf, palette)
+	case PaletteFormatACO:
+		// The ACO sections count their colors in 16 bits
+		if len(palette.Entries) > maxSwatches {
+			return fmt.Errorf("%w: ACO files hold at most %d colors, the palette has %d", ErrUnsupportedFormat, maxSwatches, len(palette.Entries))
+		}
+		encodeACO(buf, palette)
+	default:
+		return fmt.Errorf("%w: palette format %q can not be encoded as a palette file", ErrUnsupportedFormat, format)
+	}
+	return buf.Flush()
+}
+
+// SavePalette saves the palette file on the output file path.
+func SavePalette(palette Palette, outPutFilePath string, format PaletteFormat, name string) (err error) {
+	file, err := os.Create(outPutFilePath)
+	if err != nil {
+		return err
+	}
+	defer func(file *os.File) {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}(file)
+
+	return EncodePalette(file, palette, format, name)
+}
+
+func encodeGPL(w *bufio.Writer, palette Palette, name string) {
+	fmt.Fprintln(w, "GIMP Palette")
+	fmt.Fprintf(w, "Name: %s\n", name)
+	fmt.Fprintf(w, "Columns: %d\n", min(len(palette.Entries), 16))
+	fmt.Fprintln(w, "#")
+	for _, e := range palette.Entries {
+		fmt.Fprintf(w, "%3d %3d %3d\t%s\n", e.Color.R, e.Color.G, e.Color.B, entryName(e))
+	}
+}
+
+// encodePaintNET writes the colors as AARRGGBB. Paint.NET reads at most 96 colors.
+func encodePaintNET(w *bufio.Writer, palette Palette, name string) {
+	fmt.Fprintln(w, "; paint.net Palette File")
+	fmt.Fprintf(w, "; Palette Name: %s\n", name)
+	fmt.Fprintf(w, "; Colors: %d\n", min(len(palette.Entries), paintNETMaxColors))
+	for i, e := range palette.Entries {
+		if i == paintNETMaxColors {
+			break
+		}
+		fmt.Fprintf(w, "%02X%02X%02X%02X\n", e.Color.A, e.Color.R, e.Color.G, e.Color.B)
+	}
+}
+
+// encodeJASC writes a JASC-PAL file, which uses CRLF line endings.
+func encodeJASC(w *bufio.Writer, palette Palette) {
+	fmt.Fprint(w, "JASC-PAL\r\n0100\r\n")
+	fmt.Fprintf(w, "%d\r\n", len(palette.Entries))
+	for _, e := range palette.Entries {
+		fmt.Fprintf(w, "%d %d %d\r\n", e.Color.R, e.Color.G, e.Color.B)
+	}
+}
+
+func encodeHex(w *bufio.Writer, palette Palette) {
+	for _, e := range palette.Entries {
+		fmt.Fprintf(w, "%02x%02x%02x\n", e.Color.R, e.Color.G, e.Color.B)
+	}
+}
+
+// LoadPalette reads a palette file, detecting the format by the extension of the file path.
+// Files with an unknown extension are read as images of swatches, like the ones saved by
+// extract-palette, see PaletteFromImage.
+func LoadPalette(filePath string) (Palette, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return Palette{}, fmt.Errorf("opening the palette: %w", err)
+	}
+	defer file.Close()
+
+	palette, err := DecodePalette(file, PaletteFormatFromPath(filePath))
+	if err != nil {
+		return Palette{}, fmt.Errorf("reading the palette %s: %w", filePath, err)
+	}
+	return palette, nil
+}
+
+// DecodePalette reads a palette in the given format. PNG palettes are read with PaletteFromImage.
+// The entries of the decoded palette have no pixel count.
+func DecodePalette(r io.Reader, format PaletteFormat) (Palette, error) {
+	switch format {
+	case PaletteFormatPNG:
+		img, _, err := image.Decode(r)
+		if err != nil {
+			return Palette{}, decodeError(err)
+		}
+		return PaletteFromImage(img)
+	case PaletteFormatGPL:
+		return decodeGPL(r)
+	case PaletteFormatPaintNET:
+		return decodePaintNET(r)
+	case PaletteFormatJASC:
+		return decodeJASC(r)
+	case PaletteFormatHex:
+		return decodeHex(r)
+	case PaletteFormatASE:
+		return DecodeASE(r)
+	case PaletteFormatACO:
+		return DecodeACO(r)
+	default:
+		return Palette{}, fmt.Errorf("%w: palette format %q can not be decoded", ErrUnsupportedFormat, format)
+	}
+}
+
+// PaletteFromImage reads the colors of a swatch image, like the grid drawn by RenderPalette
+// or the one pixel per color images shared on Lospec. The colors are returned in the order
+// they first appear, row by row, which is the order of the swatches of the grid.
+// Fully transparent pixels, the empty cells of the grid, are skipped.
+// The labels of the sheets drawn with PaletteLayout.Labels are skipped too: every swatch
+// large enough for a label is found from its top-left pixel, which the label never covers,
+// and the black and white pixels inside it, the ink of the label, are not read as colors.
+func PaletteFromImage(img image.Image) (Palette, error) {
+	palette := Palette{}
+	seen := make(map[color.RGBA]bool)
+	bounds := img.Bounds()
+	// The smallest label has the index and the hex code of an opaque color
+	minWidth, minHeight := labelSize(Palette{Entries: []PaletteEntry{NewPaletteEntry(color.RGBA{A: 255}, 0, 0)}})
+	read := newRowReader(img)
+	row, previous := make([]color.RGBA, bounds.Dx()), make([]color.RGBA, bounds.Dx())
+	labeled := make([]bool, bounds.Dx())
+	var swatches []image.Rectangle
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		read(y, row)
+		// Marks the columns of the row inside the swatches that can have a label
+		clear(labeled)
+		swatches = slices.DeleteFunc(swatches, func(r image.Rectangle) bool { return r.Max.Y <= y })
+		for _, r := range swatches {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				labeled[x-bounds.Min.X] = true
+			}
+		}
+
+		for i, c := range row {
+			if c.A == 0 {
+				continue
+			}
+			if labeled[i] {
+				if isLabelInk(c) {
+					continue
+				}
+			} else if (i == 0 || row[i-1] != c) && (y == bounds.Min.Y || previous[i] != c) {
+				if swatch := swatchFrom(img, row, i, y, c); swatch.Dx() >= minWidth && swatch.Dy() >= minHeight {
+					swatches = append(swatches, swatch)
+				}
+			}
+			if seen[c] {
+				continue
+			}
+			if len(seen) == maxPaletteImageColors {
+				return Palette{}, fmt.Errorf("the image has more than %d colors, it is not a palette", maxPaletteImageColors)
+			}
+			seen[c] = true
+			palette.Entries = append(palette.Entries, NewPaletteEntry(c, 0, 0))
+		}
+		row, previous = previous, row
+	}
+	return palette, nil
+}
+
+// swatchFrom returns the rectangle of the color c that starts at the pixel i of the row y:
+// as wide as the run of c in the row and as tall as the run of c down the column. The top
+// row and the left column of a swatch are never covered by its label.
+func swatchFrom(img image.Image, row []color.RGBA, i, y int, c color.RGBA) image.Rectangle {
+	x := img.Bounds().Min.X + i
+	width := 1
+	for i+width < len(row) && row[i+width] == c {
+		width++
+	}
+	height := 1
+	for y+height < img.Bounds().Max.Y && rgbaAt(img, x, y+height) == c {
+		height++
+	}
+	return image.Rect(x, y, x+width, y+height)
+}
+
+// decodeGPL reads a GIMP palette: a "GIMP Palette" header, optional "Name:" and "Columns:"
+// lines, "#" comments and one "R G B [name]" color per line.
+func decodeGPL(r io.Reader) (Palette, error) {
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() || strings.TrimSpace(scanner.Text()) != "GIMP Palette" {
+		return Palette{}, fmt.Errorf("%w: not a GIMP palette", ErrUnsupportedFormat)
+	}
+
+	palette := Palette{}
+	for line := 2; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") || strings.HasPrefix(text, "Name:") || strings.HasPrefix(text, "Columns:") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) < 3 {
+			return Palette{}, fmt.Errorf("line %d: expected \"R G B [name]\", got %q", line, text)
+		}
+		c, err := parseRGBFields(fields[:3])
+		if err != nil {
+			return Palette{}, fmt.Errorf("line %d: %w", line, err)
+		}
+		entry := NewPaletteEntry(c, 0, 0)
+		entry.Name = strings.Join(fields[3:], " ")
+		palette.Entries = append(palette.Entries, entry)
+	}
+	return palette, scanner.Err()
+}
+
+// decodePaintNET reads a Paint.NET palette: ";" comments and one AARRGGBB color per line.
+func decodePaintNET(r io.Reader) (Palette, error) {
+	scanner := bufio.NewScanner(r)
+	palette := Palette{}
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, ";") {
+			continue
+		}
+		argb, err := hex.DecodeString(text)
+		if err != nil || len(argb) != 4 {
+			return Palette{}, fmt.Errorf("line %d: expected an AARRGGBB color, got %q", line, text)
+		}
+		c := color.RGBA{R: argb[1], G: argb[2], B: argb[3], A: argb[0]}
+		palette.Entries = append(palette.Entries, NewPaletteEntry(c, 0, 0))
+	}
+	return palette, scanner.Err()
+}
+
+// decodeJASC reads a JASC-PAL palette: the "JASC-PAL" and "0100" header lines, the number
+// of colors and one "R G B" color per line. A fourth alpha value is accepted.
+func decodeJASC(r io.Reader) (Palette, error) {
+	scanner := bufio.NewScanner(r)
+	header := make([]string, 0, 3)
+	for len(header) < 3 && scanner.Scan() {
+		header = append(header, strings.TrimSpace(scanner.Text()))
+	}
+	if len(header) < 3 || header[0] != "JASC-PAL" {
+		return Palette{}, fmt.Errorf("%w: not a JASC-PAL palette", ErrUnsupportedFormat)
+	}
+	count, err := strconv.Atoi(header[2])
+	if err != nil {
+		return Palette{}, fmt.Errorf("invalid JASC-PAL color count %q", header[2])
+	}
+
+	palette := Palette{}
+	for line := 4; scanner.Scan() && len(palette.Entries) < count; line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 && len(fields) != 4 {
+			return Palette{}, fmt.Errorf("line %d: expected \"R G B\", got %q", line, scanner.Text())
+		}
+		c, err := parseRGBFields(fields)
+		if err != nil {
+			return Palette{}, fmt.Errorf("line %d: %w", line, err)
+		}
+		palette.Entries = append(palette.Entries, NewPaletteEntry(c, 0, 0))
+	}
+	if err := scanner.Err(); err != nil {
+		return Palette{}, err
+	}
+	if len(palette.Entries) != count {
+		return Palette{}, fmt.Errorf("JASC-PAL palette declares %d colors but has %d", count, len(palette.Entries))
+	}
+	return palette, nil
+}
+
+// decodeHex reads one "rrggbb" or "rrggbbaa" color per line, with or without a leading "#".
+// Blank lines and lines starting with ";" or "//" are skipped.
+func decodeHex(r io.Reader) (Palette, error) {
+	scanner := bufio.NewScanner(r)
+	palette := Palette{}
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, ";") || strings.HasPrefix(text, "//") {
+			continue
+		}
+		c, err := ParseHexColor(text)
+		if err != nil {
+			return Palette{}, fmt.Errorf("line %d: %w", line, err)
+		}
+		palette.Entries = append(palette.Entries, NewPaletteEntry(c, 0, 0))
+	}
+	return palette, scanner.Err()
+}
+
+// ParseHexColor parses a "#rrggbb" or "#rrggbbaa" color, the leading "#" is optional.
+func ParseHexColor(s string) (color.RGBA, error) {
+	values, err := hex.DecodeString(strings.TrimPrefix(s, "#"))
+	if err != nil || (len(values) != 3 && len(values) != 4) {
+		return color.RGBA{}, fmt.Errorf("invalid hex color %q", s)
+	}
+	c := color.RGBA{R: values[0], G: values[1], B: values[2], A: 255}
+	if len(values) == 4 {
+		c.A = values[3]
+	}
+	return c, nil
+}
+
+// parseRGBFields parses "R G B" and optionally "A" decimal fields from 0 to 255.
+func parseRGBFields(fields []string) (color.RGBA, error) {
+	values := [4]uint8{0, 0, 0, 255}
+	for i, f := range fields {
+		v, err := strconv.ParseUint(f, 10, 8)
+		if err != nil {
+			return color.RGBA{}, fmt.Errorf("invalid color channel %q", f)
+		}
+		values[i] = uint8(v)
+	}
+	return color.RGBA{R: values[0], G: values[1], B: values[2], A: values[3]}, nil
+}
@@ -0,0 +1,326 @@
+package pixelforging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+// testPalette is a palette of opaque named colors, which every palette file format keeps.
+func testPalette() Palette {
+	colors := []color.RGBA{
+		{R: 0, G: 0, B: 0, A: 255},
+		{R: 255, G: 255, B: 255, A: 255},
+		{R: 190, G: 38, B: 51, A: 255},
+		{R: 49, G: 162, B: 242, A: 255},
+		{R: 1, G: 128, B: 254, A: 255},
+	}
+	palette := Palette{}
+	for i, c := range colors {
+		entry := NewPaletteEntry(c, 0, 0)
+		if i%2 == 0 {
+			entry.Name = "Swatch " + entry.Hex
+		}
+		palette.Entries = append(palette.Entries, entry)
+	}
+	return palette
+}
+
+// decodableFormats are the palette file formats that list colors and can be read back.
+var decodableFormats = []PaletteFormat{
+	PaletteFormatGPL,
+	PaletteFormatPaintNET,
+	PaletteFormatJASC,
+	PaletteFormatHex,
+	PaletteFormatASE,
+	PaletteFormatACO,
+}
+
+func TestEncodePalette(t *testing.T) {
+	palette := Palette{Entries: []PaletteEntry{
+		NewPaletteEntry(color.RGBA{R: 190, G: 38, B: 51, A: 255}, 0, 0),
+		NewPaletteEntry(color.RGBA{R: 1, G: 2, B: 3, A: 128}, 0, 0),
+	}}
+	palette.Entries[0].Name = "Red"
+	tests := []struct {
+		format PaletteFormat
+		want   string
+	}{
+		{PaletteFormatGPL, "GIMP Palette\nName: Sprite\nColumns: 2\n#\n190  38  51\tRed\n  1   2   3\t#01020380\n"},
+		{PaletteFormatPaintNET, "; paint.net Palette File\n; Palette Name: Sprite\n; Colors: 2\nFFBE2633\n80010203\n"},
+		{PaletteFormatJASC, "JASC-PAL\r\n0100\r\n2\r\n190 38 51\r\n1 2 3\r\n"},
+		{PaletteFormatHex, "be2633\n010203\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := EncodePalette(&buf, palette, tt.format, "Sprite"); err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.format, buf.String(), tt.want)
+		}
+	}
+
+	for _, format := range []PaletteFormat{PaletteFormatPNG, PaletteFormatSVG, "bmp"} {
+		if err := EncodePalette(&bytes.Buffer{}, palette, format, "Sprite"); !errors.Is(err, ErrUnsupportedFormat) {
+			t.Errorf("%s: got %v, want ErrUnsupportedFormat", format, err)
+		}
+	}
+}
+
+func TestPaletteFormatFromPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want PaletteFormat
+	}{
+		{"palette.gpl", PaletteFormatGPL},
+		{"palette.TXT", PaletteFormatPaintNET},
+		{"dir.v2/palette.pal", PaletteFormatJASC},
+		{"palette.hex", PaletteFormatHex},
+		{"palette.ase", PaletteFormatASE},
+		{"palette.aco", PaletteFormatACO},
+		{"palette.htm", PaletteFormatHTML},
+		{"palette.svg", PaletteFormatSVG},
+		// Unknown extensions are read as swatch images
+		{"palette.jpeg", PaletteFormatPNG},
+		{"palette", PaletteFormatPNG},
+	}
+	for _, tt := range tests {
+		if got := PaletteFormatFromPath(tt.path); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParsePaletteFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    PaletteFormat
+		wantErr error
+	}{
+		{"gpl", PaletteFormatGPL, nil},
+		{"PaintNET", PaletteFormatPaintNET, nil},
+		{"html", PaletteFormatHTML, nil},
+		{"txt", "", ErrUnsupportedFormat},
+		{"", "", ErrUnsupportedFormat},
+	}
+	for _, tt := range tests {
+		got, err := ParsePaletteFormat(tt.name)
+		if got != tt.want || !errors.Is(err, tt.wantErr) {
+			t.Errorf("%q: got %q, %v, want %q, %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestPaletteRoundTrip(t *testing.T) {
+	namedFormats := map[PaletteFormat]bool{PaletteFormatGPL: true, PaletteFormatASE: true, PaletteFormatACO: true}
+	want := testPalette()
+	for _, format := range decodableFormats {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := EncodePalette(&buf, want, format, "Test"); err != nil {
+				t.Fatalf("EncodePalette: %v", err)
+			}
+			got, err := DecodePalette(&buf, format)
+			if err != nil {
+				t.Fatalf("DecodePalette: %v", err)
+			}
+			if len(got.Entries) != len(want.Entries) {
+				t.Fatalf("decoded %d colors, want %d", len(got.Entries), len(want.Entries))
+			}
+			for i, e := range got.Entries {
+				if e.Color != want.Entries[i].Color {
+					t.Errorf("color %d is %v, want %v", i, e.Color, want.Entries[i].Color)
+				}
+				if namedFormats[format] && e.Name != entryName(want.Entries[i]) {
+					t.Errorf("color %d is named %q, want %q", i, e.Name, entryName(want.Entries[i]))
+				}
+			}
+		})
+	}
+}
+
+func TestPaletteRoundTripAlpha(t *testing.T) {
+	want := Palette{Entries: []PaletteEntry{
+		NewPaletteEntry(color.RGBA{R: 10, G: 20, B: 30, A: 128}, 0, 0),
+		NewPaletteEntry(color.RGBA{A: 0}, 0, 0),
+	}}
+	var buf bytes.Buffer
+	if err := EncodePalette(&buf, want, PaletteFormatPaintNET, "Test"); err != nil {
+		t.Fatalf("EncodePalette: %v", err)
+	}
+	got, err := DecodePalette(&buf, PaletteFormatPaintNET)
+	if err != nil {
+		t.Fatalf("DecodePalette: %v", err)
+	}
+	for i, e := range got.Entries {
+		if e.Color != want.Entries[i].Color {
+			t.Errorf("color %d is %v, want %v", i, e.Color, want.Entries[i].Color)
+		}
+	}
+}
+
+func TestEncodeACOColorLimit(t *testing.T) {
+	palette := Palette{Entries: make([]PaletteEntry, maxColorNum)}
+	for i := range palette.Entries {
+		palette.Entries[i] = PaletteEntry{Color: color.RGBA{R: uint8(i >> 8), G: uint8(i), A: 255}}
+	}
+	var buf bytes.Buffer
+	if err := EncodePalette(&buf, palette, PaletteFormatACO, "Test"); err != nil {
+		t.Fatalf("EncodePalette of %d colors: %v", maxColorNum, err)
+	}
+	got, err := DecodePalette(&buf, PaletteFormatACO)
+	if err != nil {
+		t.Fatalf("DecodePalette: %v", err)
+	}
+	if len(got.Entries) != maxColorNum {
+		t.Errorf("decoded %d colors, want %d", len(got.Entries), maxColorNum)
+	}
+
+	palette.Entries = append(palette.Entries, palette.Entries[0])
+	if err := EncodePalette(&buf, palette, PaletteFormatACO, "Test"); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("EncodePalette of %d colors returned %v, want ErrUnsupportedFormat", len(palette.Entries), err)
+	}
+}
+
+// hugeASEBlock is an ASE file with a single block that declares a length of 4 GiB.
+func hugeASEBlock() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("ASEF")
+	binary.Write(&buf, binary.BigEndian, [2]uint16{1, 0})
+	binary.Write(&buf, binary.BigEndian, uint32(1))
+	binary.Write(&buf, binary.BigEndian, uint16(aseColorEntry))
+	binary.Write(&buf, binary.BigEndian, uint32(0xFFFFFFFF))
+	return buf.Bytes()
+}
+
+func FuzzDecodePalette(f *testing.F) {
+	for i, format := range decodableFormats {
+		var buf bytes.Buffer
+		if err := EncodePalette(&buf, testPalette(), format, "Test"); err != nil {
+			f.Fatalf("EncodePalette(%s): %v", format, err)
+		}
+		f.Add(uint8(i), buf.Bytes())
+	}
+	f.Add(uint8(4), hugeASEBlock())
+	f.Add(uint8(5), []byte{0, 1, 0xFF, 0xFF})
+
+	f.Fuzz(func(t *testing.T, formatIndex uint8, data []byte) {
+		format := decodableFormats[int(formatIndex)%len(decodableFormats)]
+		palette, err := DecodePalette(bytes.NewReader(data), format)
+		if err != nil {
+			return
+		}
+		var buf bytes.Buffer
+		if err := EncodePalette(&buf, palette, format, "Fuzz"); err != nil {
+			t.Fatalf("EncodePalette(%s) of a decoded palette: %v", format, err)
+		}
+	})
+}
+
+func TestPaletteFromImage(t *testing.T) {
+	palette := testPalette()
+	palette.TotalPixels = 100
+	for i := range palette.Entries {
+		palette.Entries[i] = NewPaletteEntry(palette.Entries[i].Color, 20, palette.TotalPixels)
+	}
+	// The labels are written in black and white, the colors of the palette are not
+	colorful := Palette{Entries: palette.Entries[2:], TotalPixels: palette.TotalPixels}
+	tests := []struct {
+		name    string
+		palette Palette
+		layout  PaletteLayout
+	}{
+		{"grid", palette, PaletteLayout{ColorsPerRow: 2}},
+		{"labeled grid", palette, PaletteLayout{ColorsPerRow: 2, Labels: true}},
+		{"labeled grid without black and white", colorful, PaletteLayout{ColorsPerRow: 2, Labels: true}},
+		{"labeled small blocks", colorful, PaletteLayout{ColorsPerRow: 3, ColorWidth: 1, ColorHeight: 1, Labels: true}},
+		{"labeled bar", colorful, PaletteLayout{Mode: LayoutBar, ColorWidth: 20, Labels: true}},
+		{"one pixel per color", palette, PaletteLayout{ColorsPerRow: len(palette.Entries), ColorWidth: 1, ColorHeight: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			palette := tt.palette
+			img, err := RenderPalette(palette, tt.layout)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := PaletteFromImage(img)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got.Entries) != len(palette.Entries) {
+				t.Fatalf("read %d colors, want %d: %v", len(got.Entries), len(palette.Entries), got.Colors())
+			}
+			for i, e := range got.Entries {
+				if e.Color != palette.Entries[i].Color {
+					t.Errorf("color %d is %v, want %v", i, e.Color, palette.Entries[i].Color)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeHandWrittenPalettes(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{G: 16, B: 255, A: 255}
+	translucentBlue := color.RGBA{G: 16, B: 255, A: 128}
+	tests := []struct {
+		format    PaletteFormat
+		data      string
+		want      []color.RGBA
+		wantNames []string
+	}{
+		{PaletteFormatGPL, "GIMP Palette\nName: Hand\n\n# comment\n255 0 0 Dark Red\n  0  16 255\n", []color.RGBA{red, blue}, []string{"Dark Red", ""}},
+		{PaletteFormatPaintNET, "; comment\n\nFFFF0000\n800010ff\n", []color.RGBA{red, translucentBlue}, nil},
+		{PaletteFormatJASC, "JASC-PAL\r\n0100\r\n2\r\n255 0 0\r\n\r\n0 16 255 128\r\n", []color.RGBA{red, translucentBlue}, nil},
+		{PaletteFormatHex, "// comment\n#ff0000\n; comment\n0010ff80\n", []color.RGBA{red, translucentBlue}, nil},
+	}
+	for _, tt := range tests {
+		got, err := DecodePalette(strings.NewReader(tt.data), tt.format)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		if len(got.Entries) != len(tt.want) {
+			t.Fatalf("%s: got %v, want %v", tt.format, got.Colors(), tt.want)
+		}
+		for i, e := range got.Entries {
+			if e.Color != tt.want[i] || e.Count != 0 {
+				t.Errorf("%s: color %d is %v with %d pixels, want %v with none", tt.format, i, e.Color, e.Count, tt.want[i])
+			}
+			if tt.wantNames != nil && e.Name != tt.wantNames[i] {
+				t.Errorf("%s: color %d is named %q, want %q", tt.format, i, e.Name, tt.wantNames[i])
+			}
+		}
+	}
+}
+
+func TestDecodePaletteErrors(t *testing.T) {
+	tests := []struct {
+		format  PaletteFormat
+		data    string
+		wantErr error
+	}{
+		{PaletteFormatGPL, "Not a palette\n", ErrUnsupportedFormat},
+		{PaletteFormatGPL, "GIMP Palette\n255 0\n", nil},
+		{PaletteFormatGPL, "GIMP Palette\n256 0 0\n", nil},
+		{PaletteFormatPaintNET, "FF0000\n", nil},
+		{PaletteFormatJASC, "JASC-PAL\n0100\n", ErrUnsupportedFormat},
+		{PaletteFormatJASC, "JASC-PAL\n0100\nmany\n", nil},
+		{PaletteFormatJASC, "JASC-PAL\n0100\n2\n255 0 0\n", nil},
+		{PaletteFormatHex, "#ff00\n", nil},
+		{PaletteFormatPNG, "not an image", ErrUnsupportedFormat},
+		{PaletteFormatSVG, "<svg/>", ErrUnsupportedFormat},
+	}
+	for _, tt := range tests {
+		_, err := DecodePalette(strings.NewReader(tt.data), tt.format)
+		if err == nil {
+			t.Errorf("%s %q: got no error", tt.format, tt.data)
+		} else if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s %q: got %v, want %v", tt.format, tt.data, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package pixelforging
+
+import "fmt"
+
+// PaletteOptions configures the extraction of a palette. The zero value of every field
+// uses its default, see DefaultPaletteOptions.
+type PaletteOptions struct {
+	// Algorithm is the name of the quantizer, see NewQuantizer. Empty uses "frequency".
+	Algorithm string
+	// Quantizer, when set, is used instead of the one selected by Algorithm.
+	Quantizer Quantizer
+	// ColorNum is the number of colors of the palette, 0 uses the default (6).
+	ColorNum int
+	// Seed of the k-means initialization, 0 picks a random seed.
+	Seed int64
+	// OctreeDepth is the depth of the octree, from 1 to 8, 0 uses the maximum depth.
+	OctreeDepth int
+	// MergeThreshold is the Delta-E under which the frequency algorithm merges two colors,
+	// 0 disables the merge.
+	MergeThreshold float64
+	// Metric is the Delta-E formula used to compare colors, empty uses CIEDE2000.
+	Metric DeltaEFormula
+	// Sort is the order of the colors of the palette, empty uses SortHue.
+	Sort SortOrder
+	// Alpha configures how the transparency of the pixels is handled.
+	Alpha AlphaOptions
+	// HistogramBits is the number of bits kept of each RGB channel when the colors are
+	// counted, from 1 to 8. 0 keeps the exact colors. It is not used when the palette is
+	// extracted from a histogram, that already has its bits.
+	HistogramBits int
+	// Shortage is what is done when the image has fewer colors than ColorNum, empty uses
+	// ShortageAvailable.
+	Shortage ShortagePolicy
+	// Layout is the shape of the image drawn by RenderPalette.
+	Layout PaletteLayout
+}
+
+// PaletteLayout is the shape of the image of color blocks drawn by RenderPalette. Zero sizes
+// use the defaults (3 colors per row, 50x50 blocks) and an empty mode uses LayoutGrid.
+type PaletteLayout struct {
+	Mode         LayoutMode
+	ColorsPerRow int
+	ColorWidth   int
+	ColorHeight  int
+	// Labels writes the index, hex code and coverage of every color under its block, so the
+	// image can be used as a style guide sheet.
+	Labels bool
+}
+
+// LayoutMode selects how RenderPalette arranges the color blocks.
+type LayoutMode string
+
+// Supported layout modes.
+const (
+	// LayoutGrid draws the colors in order, ColorsPerRow blocks per row.
+	LayoutGrid LayoutMode = "grid"
+	// LayoutRamps draws every ramp of the palette, see Palette.Ramps, in its own row, from
+	// the shadow to the highlight. ColorsPerRow is not used.
+	LayoutRamps LayoutMode = "ramps"
+	// LayoutBar draws the colors side by side in a single strip, each one as wide as its
+	// share of the pixels of the image, so the dominant colors stand out. The strip is as wide
+	// as the colors would be in a single row of the grid. ColorsPerRow is not used.
+	LayoutBar LayoutMode = "bar"
+)
+
+// ParseLayoutMode validates a layout mode name. An empty name selects LayoutGrid.
+func ParseLayoutMode(name string) (LayoutMode, error) {
+	switch mode := LayoutMode(name); mode {
+	case "":
+		return LayoutGrid, nil
+	case LayoutGrid, LayoutRamps, LayoutBar:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("%w: unknown palette layout %q", ErrInvalidOption, name)
+	}
+}
+
+// Limits of the options, so a request can not make the palette, or the image drawn from it,
+// take an absurd amount of memory.
+const (
+	// maxColorNum is the most colors a palette can have, the most ACO files hold.
+	maxColorNum = maxSwatches
+	// maxColorsPerRow is the most color blocks of a row of the grid.
+	maxColorsPerRow = 1 << 10
+	// maxColorBlockSize is the largest width or height of a color block, in pixels.
+	maxColorBlockSize = 1 << 12
+	// maxSheetArea is the largest area, in pixels, of the image drawn from a palette.
+	maxSheetArea = 1 << 26
+)
+
+// DefaultPaletteOptions returns the options used when the fields of PaletteOptions are zero,
+// filled in, so they can be shown to the user or changed one by one.
+func DefaultPaletteOptions() PaletteOptions {
+	return PaletteOptions{
+		Algorithm:     AlgorithmFrequency,
+		ColorNum:      colorNumDefault,
+		OctreeDepth:   octreeMaxDepth,
+		Metric:        DeltaE2000,
+		Sort:          SortHue,
+		Alpha:         AlphaOptions{Policy: AlphaIgnore, Threshold: alphaThresholdDefault},
+		HistogramBits: histogramMaxBits,
+		Shortage:      ShortageAvailable,
+		Layout:        PaletteLayout{Mode: LayoutGrid, ColorsPerRow: colorsPerRowDefault, ColorWidth: colorBlockWidth, ColorHeight: colorBlockHeight},
+	}
+}
+
+// Validate checks the options, returning an error that wraps ErrInvalidOption or
+// ErrInvalidDimensions for the first invalid one.
+func (o PaletteOptions) Validate() error {
+	if o.ColorNum < 0 || o.ColorNum > maxColorNum {
+		return fmt.Errorf("%w: the number of colors should be between 0 and %d, got %d", ErrInvalidOption, maxColorNum, o.ColorNum)
+	}
+	if _, err := ParseDeltaEFormula(string(o.Metric)); err != nil {
+		return err
+	}
+	quantizer, err := o.quantizer()
+	if err != nil {
+		return err
+	}
+	order, err := ParseSortOrder(string(o.Sort))
+	if err != nil {
+		return err
+	}
+	if order == SortNearest && o.ColorNum > maxNearestColors {
+		return fmt.Errorf("%w: the %q sort order can only order up to %d colors, got %d", ErrInvalidOption, SortNearest, maxNearestColors, o.ColorNum)
+	}
+	alpha, err := ParseAlphaPolicy(string(o.Alpha.Policy))
+	if err != nil {
+		return err
+	}
+	if alpha == AlphaKeep && !keepsAlpha(quantizer) {
+		return fmt.Errorf("%w: the %q alpha policy is only supported by the %q and %q algorithms", ErrInvalidOption, AlphaKeep, AlgorithmMedianCut, AlgorithmFrequency)
+	}
+	if o.HistogramBits < 0 || o.HistogramBits > histogramMaxBits {
+		return fmt.Errorf("%w: the histogram bits should be between 1 and %d, got %d", ErrInvalidOption, histogramMaxBits, o.HistogramBits)
+	}
+	shortage, err := ParseShortagePolicy(string(o.Shortage))
+	if err != nil {
+		return err
+	}
+	if shortage == ShortagePad && o.ColorNum > maxPadColors {
+		return fmt.Errorf("%w: palettes can only be padded up to %d colors, got %d", ErrInvalidOption, maxPadColors, o.ColorNum)
+	}
+	return o.Layout.Validate()
+}
+
+// quantizer returns the Quantizer of the options.
+func (o PaletteOptions) quantizer() (Quantizer, error) {
+	if o.Quantizer != nil {
+		return o.Quantizer, nil
+	}
+	return NewQuantizer(o.Algorithm, QuantizerOptions{
+		Seed:           o.Seed,
+		OctreeDepth:    o.OctreeDepth,
+		MergeThreshold: o.MergeThreshold,
+		DeltaE:         o.Metric,
+	})
+}
+
+// Validate checks the mode of the layout and that its sizes are neither negative nor larger
+// than the limits: 1024 colors per row and 4096 pixels of width and height.
+func (l PaletteLayout) Validate() error {
+	if _, err := ParseLayoutMode(string(l.Mode)); err != nil {
+		return err
+	}
+	if l.ColorsPerRow < 0 || l.ColorWidth < 0 || l.ColorHeight < 0 {
+		return fmt.Errorf("%w: the colors per row, width and height can not be negative, got %d, %d and %d", ErrInvalidDimensions, l.ColorsPerRow, l.ColorWidth, l.ColorHeight)
+	}
+	if l.ColorsPerRow > maxColorsPerRow {
+		return fmt.Errorf("%w: the colors per row can not be more than %d, got %d", ErrInvalidDimensions, maxColorsPerRow, l.ColorsPerRow)
+	}
+	if l.ColorWidth > maxColorBlockSize || l.ColorHeight > maxColorBlockSize {
+		return fmt.Errorf("%w: the width and height can not be more than %d, got %d and %d", ErrInvalidDimensions, maxColorBlockSize, l.ColorWidth, l.ColorHeight)
+	}
+	return nil
+}
+
+// withDefaults returns the layout with the zero sizes replaced by the defaults.
+func (l PaletteLayout) withDefaults() PaletteLayout {
+	if l.Mode == "" {
+		l.Mode = LayoutGrid
+	}
+	if l.ColorsPerRow == 0 {
+		l.ColorsPerRow = colorsPerRowDefault
+	}
+	if l.ColorWidth == 0 {
+		l.ColorWidth = colorBlockWidth
+	}
+	if l.ColorHeight == 0 {
+		l.ColorHeight = colorBlockHeight
+	}
+	return l
+}
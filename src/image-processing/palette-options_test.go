@@ -0,0 +1,97 @@
+package pixelforging
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidatePaletteOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options PaletteOptions
+		wantErr error
+	}{
+		{"zero value", PaletteOptions{}, nil},
+		{"defaults", DefaultPaletteOptions(), nil},
+		{"most colors", PaletteOptions{ColorNum: maxColorNum}, nil},
+		{"too many colors", PaletteOptions{ColorNum: maxColorNum + 1}, ErrInvalidOption},
+		{"negative colors", PaletteOptions{ColorNum: -1}, ErrInvalidOption},
+		{"unknown algorithm", PaletteOptions{Algorithm: "popularity"}, ErrInvalidOption},
+		{"octree too deep", PaletteOptions{Algorithm: AlgorithmOctree, OctreeDepth: 9}, ErrInvalidOption},
+		{"unknown Delta-E", PaletteOptions{Metric: "cie2020"}, ErrInvalidOption},
+		{"unknown sort", PaletteOptions{Sort: "random"}, ErrInvalidOption},
+		{"unknown alpha policy", PaletteOptions{Alpha: AlphaOptions{Policy: "invert"}}, ErrInvalidOption},
+		{"histogram bits", PaletteOptions{HistogramBits: 5}, nil},
+		{"too many histogram bits", PaletteOptions{HistogramBits: 9}, ErrInvalidOption},
+		{"negative histogram bits", PaletteOptions{HistogramBits: -1}, ErrInvalidOption},
+		{"unknown shortage policy", PaletteOptions{Shortage: "shrink"}, ErrInvalidOption},
+		{"unknown layout", PaletteOptions{Layout: PaletteLayout{Mode: "circle"}}, ErrInvalidOption},
+		{"negative width", PaletteOptions{Layout: PaletteLayout{ColorWidth: -1}}, ErrInvalidDimensions},
+		{"largest blocks", PaletteOptions{Layout: PaletteLayout{ColorWidth: maxColorBlockSize, ColorHeight: maxColorBlockSize}}, nil},
+		{"block too tall", PaletteOptions{Layout: PaletteLayout{ColorHeight: maxColorBlockSize + 1}}, ErrInvalidDimensions},
+		{"too many colors per row", PaletteOptions{Layout: PaletteLayout{ColorsPerRow: maxColorsPerRow + 1}}, ErrInvalidDimensions},
+	}
+	for _, tt := range tests {
+		if err := tt.options.Validate(); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseLayoutMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    LayoutMode
+		wantErr error
+	}{
+		{"", LayoutGrid, nil},
+		{"grid", LayoutGrid, nil},
+		{"ramps", LayoutRamps, nil},
+		{"bar", LayoutBar, nil},
+		{"Bar", "", ErrInvalidOption},
+	}
+	for _, tt := range tests {
+		got, err := ParseLayoutMode(tt.name)
+		if got != tt.want || !errors.Is(err, tt.wantErr) {
+			t.Errorf("%q: got %q, %v, want %q, %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestDefaultPaletteOptions(t *testing.T) {
+	// The defaults filled in give the same palette as the zero value
+	img := randomImage(20, 20, 14)
+	zero, err := ExtractPalette(img, PaletteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaults, err := ExtractPalette(img, DefaultPaletteOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zero.Entries) != len(defaults.Entries) {
+		t.Fatalf("got %d colors with the defaults, want %d", len(defaults.Entries), len(zero.Entries))
+	}
+	for i := range zero.Entries {
+		if zero.Entries[i] != defaults.Entries[i] {
+			t.Errorf("color %d is %+v with the defaults, want %+v", i, defaults.Entries[i], zero.Entries[i])
+		}
+	}
+
+	if got := (PaletteLayout{}).withDefaults(); got != DefaultPaletteOptions().Layout {
+		t.Errorf("the zero layout with defaults is %+v, want %+v", got, DefaultPaletteOptions().Layout)
+	}
+}
+
+func TestRenderPaletteSheetArea(t *testing.T) {
+	palette := sequentialPalette(maxColorsPerRow)
+	// 1024 blocks of 256x256 pixels are 64 million pixels, the largest sheet allowed
+	layout := PaletteLayout{ColorsPerRow: 64, ColorWidth: 256, ColorHeight: 256}
+	if _, err := arrangeSwatches(palette, layout); err != nil {
+		t.Errorf("a %d pixels sheet: %v", maxSheetArea, err)
+	}
+	layout.ColorHeight++
+	if _, err := RenderPalette(palette, layout); !errors.Is(err, ErrInvalidDimensions) {
+		t.Errorf("a sheet over %d pixels: got %v, want ErrInvalidDimensions", maxSheetArea, err)
+	}
+}
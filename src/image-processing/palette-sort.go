@@ -0,0 +1,181 @@
+package pixelforging
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+)
+
+// SortOrder selects the order of the colors of an extracted palette.
+type SortOrder string
+
+// Supported sort orders. Colors that tie keep the frequency order.
+const (
+	// SortHue orders the colors by hue and then by lightness.
+	SortHue SortOrder = "hue"
+	// SortFrequency orders the colors by the number of pixels they represent, from the most frequent.
+	SortFrequency SortOrder = "frequency"
+	// SortLuminance orders the colors by their Rec.709 relative luminance, from the darkest.
+	SortLuminance SortOrder = "luminance"
+	// SortHueRamps groups the colors by hue family, the grays first and then every 30 degrees
+	// of hue starting at red, and orders each family from the darkest to the lightest, so the
+	// ramps of the palette read one after the other.
+	SortHueRamps SortOrder = "hue-ramps"
+	// SortLightness orders the colors by their CIELAB lightness (L*), from the darkest.
+	SortLightness SortOrder = "lightness"
+	// SortNearest starts at the darkest color and repeatedly picks the nearest color not
+	// picked yet, measured with the Delta-E formula of the options, so the palette reads as a
+	// smooth gradient. It orders palettes of up to 1024 colors.
+	SortNearest SortOrder = "nearest-neighbor"
+	// SortFirstSeen orders the colors by the first pixel they represent, reading the image
+	// row by row, the order an artist painted them in a sprite sheet.
+	SortFirstSeen SortOrder = "first-seen"
+)
+
+const (
+	// hueFamilies is the number of hue families of SortHueRamps, 30 degrees each.
+	hueFamilies = 12
+	// neutralChroma is the CIELAB chroma under which a color belongs to the gray family.
+	neutralChroma = 10
+	// maxNearestColors is the most colors SortNearest can order. The path compares every
+	// color with all the colors not visited yet, half a million Delta-E for 1024 colors.
+	maxNearestColors = 1 << 10
+)
+
+// ParseSortOrder validates a sort order name. An empty name selects SortHue.
+func ParseSortOrder(name string) (SortOrder, error) {
+	switch order := SortOrder(name); order {
+	case "":
+		return SortHue, nil
+	case SortHue, SortFrequency, SortLuminance, SortHueRamps, SortLightness, SortNearest, SortFirstSeen:
+		return order, nil
+	default:
+		return "", fmt.Errorf("%w: unknown sort order %q", ErrInvalidOption, name)
+	}
+}
+
+// sortColors returns the colors of the quantized palette, sorted by pixel count, in the order.
+// The histogram the palette was extracted from gives the first-seen order, and the formula
+// measures the distances of the nearest-neighbor order.
+func sortColors(quantized []ColorCount, order SortOrder, histogram *Histogram, formula DeltaEFormula) []color.RGBA {
+	colors := colorsOf(quantized)
+	switch order {
+	case SortHue:
+		return organizeColorsByHSL(colors)
+	case SortLuminance:
+		sortColorsBy(colors, luminance)
+	case SortHueRamps:
+		sortColorsBy(colors, func(c color.RGBA) float64 {
+			// The family is the integer part of the key and the lightness, from 0 to 100, the fraction
+			return float64(hueFamily(c)) + RGBAToLab(c).L/101
+		})
+	case SortLightness:
+		sortColorsBy(colors, func(c color.RGBA) float64 { return RGBAToLab(c).L })
+	case SortNearest:
+		colors = nearestNeighborPath(colors, formula)
+	case SortFirstSeen:
+		colors = sortByFirstSeen(colors, histogram)
+	}
+	return colors
+}
+
+// sortColorsBy sorts the colors by the key, from the smallest.
+func sortColorsBy(colors []color.RGBA, key func(c color.RGBA) float64) {
+	keys := make(map[color.RGBA]float64, len(colors))
+	for _, c := range colors {
+		keys[c] = key(c)
+	}
+	sort.SliceStable(colors, func(i, j int) bool {
+		return keys[colors[i]] < keys[colors[j]]
+	})
+}
+
+// luminance returns the Rec.709 relative luminance of the color, from 0 to 1.
+func luminance(c color.RGBA) float64 {
+	r := srgbToLinear(float64(c.R) / 255)
+	g := srgbToLinear(float64(c.G) / 255)
+	b := srgbToLinear(float64(c.B) / 255)
+	return 0.2126*r + 0.7152*g + 0.0722*b
+}
+
+// hueFamily returns the hue family of the color: 0 for the grays and 1 to 12 for every 30
+// degrees of hue, the first one centered at red.
+func hueFamily(c color.RGBA) int {
+	lab := RGBAToLab(c)
+	if math.Hypot(lab.A, lab.B) < neutralChroma {
+		return 0
+	}
+	h, _, _ := RGBAToHSL(c)
+	width := 360.0 / hueFamilies
+	return int(math.Mod(h+width/2, 360)/width) + 1
+}
+
+// nearestNeighborPath orders the colors as a path that starts at the darkest one and always
+// moves to the nearest color not visited yet.
+func nearestNeighborPath(colors []color.RGBA, formula DeltaEFormula) []color.RGBA {
+	if len(colors) == 0 {
+		return colors
+	}
+	labs := make([]LabColor, len(colors))
+	start := 0
+	for i, c := range colors {
+		labs[i] = RGBAToLab(c)
+		if labs[i].L < labs[start].L {
+			start = i
+		}
+	}
+
+	path := make([]color.RGBA, 0, len(colors))
+	visited := make([]bool, len(colors))
+	for current := start; current >= 0; {
+		path = append(path, colors[current])
+		visited[current] = true
+		next, nextDistance := -1, math.Inf(1)
+		for i := range colors {
+			if visited[i] {
+				continue
+			}
+			if d := DeltaE(formula, labs[current], labs[i]); d < nextDistance {
+				next, nextDistance = i, d
+			}
+		}
+		current = next
+	}
+	return path
+}
+
+// sortByFirstSeen orders the colors by the first pixel of the histogram they represent.
+// Each color of the histogram is represented by the same palette color, or the nearest one
+// when the palette colors are averages, like the ones of median-cut.
+func sortByFirstSeen(colors []color.RGBA, histogram *Histogram) []color.RGBA {
+	indexes := make(map[color.RGBA]int, len(colors))
+	for i := len(colors) - 1; i >= 0; i-- {
+		indexes[colors[i]] = i
+	}
+	matcher := newColorMatcher(colors, MetricWeightedRGB)
+	first := make(map[color.RGBA]int, len(colors))
+	for bucket, position := range histogram.first {
+		c := histogram.meanColor(bucket, histogram.counts[bucket])
+		i, ok := indexes[c]
+		if !ok {
+			i = matcher.nearest(c)
+		}
+		if p, ok := first[colors[i]]; !ok || position < p {
+			first[colors[i]] = position
+		}
+	}
+
+	sort.SliceStable(colors, func(i, j int) bool {
+		pi, ok := first[colors[i]]
+		if !ok {
+			pi = math.MaxInt
+		}
+		pj, ok := first[colors[j]]
+		if !ok {
+			pj = math.MaxInt
+		}
+		return pi < pj
+	})
+	return colors
+}
@@ -0,0 +1,109 @@
+package pixelforging
+
+import (
+	"errors"
+	"image/color"
+	"testing"
+)
+
+func TestSortColors(t *testing.T) {
+	var (
+		black   = color.RGBA{A: 255}
+		gray    = color.RGBA{R: 128, G: 128, B: 128, A: 255}
+		white   = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+		red     = color.RGBA{R: 255, G: 1, B: 1, A: 255}
+		darkRed = color.RGBA{R: 128, A: 255}
+		blue    = color.RGBA{B: 255, A: 255}
+	)
+	// From the most frequent, as the quantizers return them
+	quantized := []ColorCount{{red, 6}, {blue, 5}, {gray, 4}, {white, 3}, {darkRed, 2}, {black, 1}}
+	histogram, err := NewHistogram(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []color.RGBA{white, blue, black, red, darkRed, gray} {
+		histogram.Add(c, 1)
+	}
+
+	tests := []struct {
+		order SortOrder
+		want  []color.RGBA
+	}{
+		{SortFrequency, []color.RGBA{red, blue, gray, white, darkRed, black}},
+		// Red and gray have the same hue and lightness, so they keep the frequency order
+		{SortHue, []color.RGBA{black, darkRed, red, gray, white, blue}},
+		{SortLuminance, []color.RGBA{black, darkRed, blue, red, gray, white}},
+		{SortHueRamps, []color.RGBA{black, gray, white, darkRed, red, blue}},
+		{SortLightness, []color.RGBA{black, darkRed, blue, red, gray, white}},
+		{SortNearest, []color.RGBA{black, darkRed, red, gray, white, blue}},
+		{SortFirstSeen, []color.RGBA{white, blue, black, red, darkRed, gray}},
+	}
+	for _, tt := range tests {
+		got := sortColors(append([]ColorCount(nil), quantized...), tt.order, histogram, DeltaE2000)
+		if len(got) != len(tt.want) {
+			t.Fatalf("%s: got %v, want %v", tt.order, got, tt.want)
+		}
+		for i := range tt.want {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: got %v, want %v", tt.order, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestValidateNearestColorLimit(t *testing.T) {
+	options := PaletteOptions{Sort: SortNearest, ColorNum: maxNearestColors}
+	if err := options.Validate(); err != nil {
+		t.Errorf("%d colors: %v", maxNearestColors, err)
+	}
+	options.ColorNum++
+	if err := options.Validate(); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("%d colors: got %v, want ErrInvalidOption", options.ColorNum, err)
+	}
+}
+
+func TestParseSortOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    SortOrder
+		wantErr error
+	}{
+		{"", SortHue, nil},
+		{"luminance", SortLuminance, nil},
+		{"nearest-neighbor", SortNearest, nil},
+		{"first-seen", SortFirstSeen, nil},
+		{"nearest", "", ErrInvalidOption},
+	}
+	for _, tt := range tests {
+		got, err := ParseSortOrder(tt.name)
+		if got != tt.want || !errors.Is(err, tt.wantErr) {
+			t.Errorf("%q: got %q, %v, want %q, %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSortByFirstSeenAveragedColors(t *testing.T) {
+	// The palette colors are averages that are not in the histogram, like the ones of
+	// median-cut, so each color of the histogram is matched to the nearest one
+	histogram := newTestHistogram(t,
+		ColorCount{color.RGBA{R: 250, G: 250, B: 250, A: 255}, 1},
+		ColorCount{color.RGBA{R: 10, G: 10, B: 10, A: 255}, 1},
+		ColorCount{color.RGBA{R: 200, B: 10, A: 255}, 1},
+		ColorCount{color.RGBA{R: 5, G: 5, B: 5, A: 255}, 1},
+	)
+	dark := color.RGBA{R: 8, G: 8, B: 8, A: 255}
+	light := color.RGBA{R: 245, G: 245, B: 245, A: 255}
+	red := color.RGBA{R: 210, G: 5, B: 5, A: 255}
+	// green represents no pixel, so it goes last
+	green := color.RGBA{G: 200, A: 255}
+
+	got := sortByFirstSeen([]color.RGBA{green, dark, red, light}, histogram)
+	want := []color.RGBA{light, dark, red, green}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %v, want %v", got, want)
+			break
+		}
+	}
+}
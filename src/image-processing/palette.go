@@ -0,0 +1,158 @@
+package pixelforging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// Palette is the ordered list of colors extracted from an image.
+type Palette struct {
+	Entries []PaletteEntry
+	// TotalPixels is the number of non transparent pixels of the image.
+	TotalPixels int
+}
+
+// PaletteEntry is a palette color described in the color spaces used by artists,
+// together with how much of the image it covers.
+type PaletteEntry struct {
+	// Color holds the non premultiplied channels, like the colors of the palette files,
+	// even if color.RGBA is premultiplied by convention. Convert it with color.NRGBA(c)
+	// before drawing it or passing it to a color.Model when it is not fully opaque.
+	Color color.RGBA
+	// Name of the swatch, read from or written to the palette files that support names.
+	// Extracted colors have no name and are written with their hex code.
+	Name string
+	// Group of the swatch in palette files that support groups, like Adobe Swatch Exchange.
+	Group string
+	// Hex is the color as "#rrggbb", or "#rrggbbaa" when it is not fully opaque.
+	Hex string
+	HSL HSLColor
+	Lab LabColor
+	// Count is the number of pixels of the image represented by the color.
+	Count int
+	// Percentage is the share of the non transparent pixels of the image represented by the color, from 0 to 100.
+	Percentage float64
+}
+
+// NewPaletteEntry describes a color that represents count of the totalPixels of an image.
+func NewPaletteEntry(c color.RGBA, count, totalPixels int) PaletteEntry {
+	h, s, l := RGBAToHSL(c)
+	entry := PaletteEntry{
+		Color: c,
+		Hex:   HexColor(c),
+		HSL:   HSLColor{Color: c, H: h, S: s, L: l},
+		Lab:   RGBAToLab(c),
+		Count: count,
+	}
+	if totalPixels > 0 {
+		entry.Percentage = float64(count) * 100 / float64(totalPixels)
+	}
+	return entry
+}
+
+// Colors returns the colors of the palette, in order. Like PaletteEntry.Color, they hold
+// the non premultiplied channels.
+func (p Palette) Colors() []color.RGBA {
+	colors := make([]color.RGBA, len(p.Entries))
+	for i, e := range p.Entries {
+		colors[i] = e.Color
+	}
+	return colors
+}
+
+// entryName returns the name of the entry, or its hex code when it has none.
+func entryName(e PaletteEntry) string {
+	if e.Name != "" {
+		return e.Name
+	}
+	return e.Hex
+}
+
+// HexColor formats the color as "#rrggbb", adding the alpha ("#rrggbbaa") when it is not fully opaque.
+func HexColor(c color.RGBA) string {
+	if c.A == 255 {
+		return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+	}
+	return fmt.Sprintf("#%02x%02x%02x%02x", c.R, c.G, c.B, c.A)
+}
+
+// ExtractPalette extracts up to options.ColorNum colors of the image with the algorithm of
+// the options and returns them in the sort order of the options. The transparency of the
+// pixels is handled as set by the alpha options and the shortage policy decides what
+// happens when the image has fewer colors than requested.
+func ExtractPalette(img image.Image, options PaletteOptions) (Palette, error) {
+	if err := options.Validate(); err != nil {
+		return Palette{}, err
+	}
+	histogram, err := NewHistogram(options.HistogramBits)
+	if err != nil {
+		return Palette{}, err
+	}
+	if err := histogram.AddImage(img, options.Alpha); err != nil {
+		return Palette{}, err
+	}
+	return ExtractPaletteFromHistogram(histogram, options)
+}
+
+// ExtractPaletteFromHistogram extracts the palette of the histogram, that can hold the colors
+// of many images, like ExtractPalette does for a single image. options.HistogramBits and
+// options.Alpha are not used, as the colors were already counted.
+// It returns ErrTooFewColors when the histogram has no colors, like an image that is fully
+// transparent.
+func ExtractPaletteFromHistogram(histogram *Histogram, options PaletteOptions) (Palette, error) {
+	if err := options.Validate(); err != nil {
+		return Palette{}, err
+	}
+	if histogram.Len() == 0 {
+		return Palette{}, fmt.Errorf("%w: the image has no visible pixels", ErrTooFewColors)
+	}
+	colorNum := options.ColorNum
+	if colorNum == 0 {
+		colorNum = colorNumDefault
+	}
+	quantizer, err := options.quantizer()
+	if err != nil {
+		return Palette{}, err
+	}
+
+	// No quantizer can return more colors than the histogram has, asking for fewer keeps
+	// their buffers small when a huge palette is requested for a tiny sprite
+	quantized, err := quantizer.Quantize(histogram, min(colorNum, histogram.Len()))
+	if err != nil {
+		return Palette{}, err
+	}
+	quantized = mergeDuplicateColors(quantized)
+	counts := make(map[color.RGBA]int, len(quantized))
+	for _, c := range quantized {
+		counts[c.Color] = c.Count
+	}
+
+	palette := Palette{TotalPixels: histogram.Total()}
+	order, err := ParseSortOrder(string(options.Sort))
+	if err != nil {
+		return Palette{}, err
+	}
+	formula, err := ParseDeltaEFormula(string(options.Metric))
+	if err != nil {
+		return Palette{}, err
+	}
+	for _, c := range sortColors(quantized, order, histogram, formula) {
+		palette.Entries = append(palette.Entries, NewPaletteEntry(c, counts[c], palette.TotalPixels))
+	}
+	return applyShortagePolicy(palette, colorNum, options.Shortage)
+}
+
+// RenderPalette draws the palette as color blocks arranged by the mode of the layout: a grid
+// of layout.ColorsPerRow blocks per row, one row per ramp or a bar of proportional widths.
+// When layout.Labels is set, the blocks grow to fit their labels.
+func RenderPalette(palette Palette, layout PaletteLayout) (image.Image, error) {
+	a, err := arrangeSwatches(palette, layout)
+	if err != nil {
+		return nil, err
+	}
+	if a.widths != nil {
+		return createColorBar(palette, a.widths, a.colorHeight, a.labels)
+	}
+	return createColorPalette(palette, a.rows, a.colorsPerRow, a.colorWidth, a.colorHeight, a.labels)
+}
@@ -0,0 +1,132 @@
+package pixelforging
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// randomImage returns an opaque image of random colors, the same for the same seed.
+func randomImage(width, height int, seed int64) *image.RGBA {
+	rng := rand.New(rand.NewSource(seed))
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			img.SetRGBA(x, y, color.RGBA{R: uint8(rng.Intn(256)), G: uint8(rng.Intn(256)), B: uint8(rng.Intn(256)), A: 255})
+		}
+	}
+	return img
+}
+
+func TestExtractPaletteCountsSumToTotal(t *testing.T) {
+	img := randomImage(16, 16, 1)
+	algorithms := []string{AlgorithmFrequency, AlgorithmMedianCut, AlgorithmKMeans, AlgorithmOctree, AlgorithmWu}
+	for _, algorithm := range algorithms {
+		for _, colorNum := range []int{1, 2, 6, 16, 32, 100} {
+			options := PaletteOptions{Algorithm: algorithm, ColorNum: colorNum, Seed: 1, OctreeDepth: 3, HistogramBits: 4}
+			palette, err := ExtractPalette(img, options)
+			if err != nil {
+				t.Fatalf("%s, %d colors: %v", algorithm, colorNum, err)
+			}
+			sum := 0
+			seen := make(map[color.RGBA]bool)
+			for _, e := range palette.Entries {
+				if seen[e.Color] {
+					t.Errorf("%s, %d colors: %s is in the palette twice", algorithm, colorNum, e.Hex)
+				}
+				seen[e.Color] = true
+				sum += e.Count
+			}
+			// The frequency algorithm drops the pixels of the colors it does not pick
+			if sum > palette.TotalPixels || (sum != palette.TotalPixels && algorithm != AlgorithmFrequency) {
+				t.Errorf("%s, %d colors: the counts sum to %d, want %d", algorithm, colorNum, sum, palette.TotalPixels)
+			}
+		}
+	}
+}
+
+// duplicateQuantizer splits the colors of the histogram in two halves and returns the
+// first color of the image for both of them, like averaging quantizers that round two
+// boxes to the same color.
+type duplicateQuantizer struct{}
+
+func (duplicateQuantizer) Quantize(histogram *Histogram, colorNum int) ([]ColorCount, error) {
+	colors := histogram.Colors()
+	half := histogram.Total() / 2
+	return []ColorCount{{colors[0].Color, half}, {colors[0].Color, histogram.Total() - half}}, nil
+}
+
+func TestExtractPaletteMergesDuplicateColors(t *testing.T) {
+	img := randomImage(16, 16, 1)
+	palette, err := ExtractPalette(img, PaletteOptions{Quantizer: duplicateQuantizer{}, ColorNum: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(palette.Entries) != 1 {
+		t.Fatalf("got %d colors, want 1", len(palette.Entries))
+	}
+	if e := palette.Entries[0]; e.Count != palette.TotalPixels || e.Percentage != 100 {
+		t.Errorf("the color covers %d pixels (%.2f%%), want %d (100%%)", e.Count, e.Percentage, palette.TotalPixels)
+	}
+}
+
+func TestMergeDuplicateColors(t *testing.T) {
+	red, blue := color.RGBA{R: 255, A: 255}, color.RGBA{B: 255, A: 255}
+	got := mergeDuplicateColors([]ColorCount{{red, 5}, {blue, 4}, {red, 3}})
+	want := []ColorCount{{red, 8}, {blue, 4}}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("entry %d is %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestNewPaletteEntry(t *testing.T) {
+	tests := []struct {
+		color          color.RGBA
+		count, total   int
+		wantHex        string
+		wantPercentage float64
+	}{
+		{color.RGBA{R: 255, G: 128, B: 1, A: 255}, 25, 100, "#ff8001", 25},
+		{color.RGBA{R: 16, G: 32, B: 48, A: 128}, 1, 3, "#10203080", 100.0 / 3},
+		// Palettes read from files have no pixel counts
+		{color.RGBA{A: 255}, 0, 0, "#000000", 0},
+	}
+	for _, tt := range tests {
+		e := NewPaletteEntry(tt.color, tt.count, tt.total)
+		if e.Color != tt.color || e.Hex != tt.wantHex || e.Count != tt.count || math.Abs(e.Percentage-tt.wantPercentage) > 1e-9 {
+			t.Errorf("%v: got %+v, want %s with %v%%", tt.color, e, tt.wantHex, tt.wantPercentage)
+		}
+		if e.Lab != RGBAToLab(tt.color) || e.HSL.Color != tt.color {
+			t.Errorf("%v: got Lab %+v and HSL %+v", tt.color, e.Lab, e.HSL)
+		}
+	}
+}
+
+func TestExtractPaletteEntries(t *testing.T) {
+	red, blue := color.RGBA{R: 255, A: 255}, color.RGBA{B: 255, A: 255}
+	img := image.NewRGBA(image.Rect(0, 0, 4, 1))
+	img.SetRGBA(0, 0, red)
+	img.SetRGBA(1, 0, blue)
+	img.SetRGBA(2, 0, red)
+	img.SetRGBA(3, 0, red)
+	palette, err := ExtractPalette(img, PaletteOptions{Sort: SortFrequency})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PaletteEntry{NewPaletteEntry(red, 3, 4), NewPaletteEntry(blue, 1, 4)}
+	if palette.TotalPixels != 4 || len(palette.Entries) != len(want) {
+		t.Fatalf("got %+v, want %d pixels and %+v", palette, 4, want)
+	}
+	for i := range want {
+		if palette.Entries[i] != want[i] {
+			t.Errorf("entry %d is %+v, want %+v", i, palette.Entries[i], want[i])
+		}
+	}
+}
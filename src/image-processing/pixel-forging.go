@@ -2,6 +2,7 @@ package pixelforging
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"image"
 	"image/color"
@@ -10,22 +11,17 @@ import (
 	"image/jpeg" // JPEG
 	"image/png"  // PNG
 	"io"
-	"log"
 	"math"
 	"os"
+	"path/filepath"
 	"sort"
-	"sync"
+	"strings"
 
 	bmp "golang.org/x/image/bmp"   // BMP
 	tiff "golang.org/x/image/tiff" // TIFF
 	// WebP
 )
 
-type lineProcessingUtil struct {
-	img    image.Image
-	bounds image.Rectangle
-}
-
 type HSLColor struct {
 	Color   color.RGBA
 	H, S, L float64
@@ -38,70 +34,57 @@ const (
 	colorNumDefault     = 6
 )
 
-// ListingPixels lists all the pixels in an image as a slice of `color.RGBA`.
-// This function uses a worker pool to process the image in parallel, so the order
-// of pixels in the result may not correspond to the original (row-column) order in the image.
-// Use ListingPixelsOrdered if you need the pixel order to match the image's structure.
+// ListingPixels lists all the pixels in an image as a slice of `color.RGBA`, row by row,
+// holding the non premultiplied channels, see PaletteEntry.Color.
+// The image is read in parallel, in bands of rows, and the common image types are read
+// straight from their pixel buffers.
 func ListingPixels(img image.Image) ([]color.RGBA, error) {
+	bands := scanRows(img, func() *[]color.RGBA {
+		return &[]color.RGBA{}
+	}, func(colors *[]color.RGBA, y int, row []color.RGBA) {
+		*colors = append(*colors, row...)
+	})
 
-	var wg sync.WaitGroup
 	bounds := img.Bounds()
-	colorsChan := make(chan color.RGBA, bounds.Dx()*bounds.Dy()/32)
 	colors := make([]color.RGBA, 0, bounds.Dx()*bounds.Dy())
-
-	pools := 0
-	numbersOfLines := bounds.Dy()
-
-	if numbersOfLines < 32 {
-		pools = numbersOfLines
-	} else {
-		pools = 32
-	}
-
-	linesToProcess := make(chan int, pools)
-
-	for i := 0; i < pools; i++ {
-		wg.Add(1)
-		go worker(linesToProcess, colorsChan, lineProcessingUtil{
-			img:    img,
-			bounds: bounds,
-		}, &wg)
+	for _, band := range bands {
+		colors = append(colors, *band...)
 	}
+	return colors, nil
+}
 
-	go func() {
-		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-			linesToProcess <- y
-		}
-		close(linesToProcess)
-	}()
-
-	go func() {
-		wg.Wait()
-		close(colorsChan)
-	}()
+// rgbaAt reads the pixel at (x, y) as a `color.RGBA` holding the non premultiplied
+// channels, like the colors of the palette files. The colors are converted through
+// color.NRGBA64Model, so 16-bit images are rounded to the nearest 8-bit value.
+func rgbaAt(img image.Image, x, y int) color.RGBA {
+	return to8BitRGBA(nrgba64At(img, x, y))
+}
 
-	for v := range colorsChan {
-		colors = append(colors, v)
-	}
-	return colors, nil
+// nrgba64At reads the pixel at (x, y) with the full 16-bit precision of the image.
+func nrgba64At(img image.Image, x, y int) color.NRGBA64 {
+	return toNRGBA64(img.At(x, y))
 }
 
-func worker(linesChan chan int, colorsChan chan color.RGBA, util lineProcessingUtil, wg *sync.WaitGroup) {
-	defer wg.Done()
-	for y := range linesChan {
-		getPixelsOfImageLine(util.img, y, util.bounds, colorsChan)
+// toNRGBA64 converts a color to 16-bit non premultiplied channels.
+func toNRGBA64(c color.Color) color.NRGBA64 {
+	// Non premultiplied colors are widened directly, converting them through RGBA() would
+	// lose precision on the pixels with a low alpha
+	if n, ok := c.(color.NRGBA); ok {
+		return color.NRGBA64{R: uint16(n.R) * 0x101, G: uint16(n.G) * 0x101, B: uint16(n.B) * 0x101, A: uint16(n.A) * 0x101}
 	}
-
+	return color.NRGBA64Model.Convert(c).(color.NRGBA64)
 }
 
-func getPixelsOfImageLine(img image.Image, line int, bounds image.Rectangle, colorsChan chan color.RGBA) {
-	for x := bounds.Min.X; x < bounds.Max.X; x++ {
-		r, g, b, a := img.At(x, line).RGBA()
-		colorsChan <- color.RGBA{R: uint8(r), G: uint8(g), B: uint8(b), A: uint8(a)}
+// to8BitRGBA rounds the channels of a 16-bit color to 8 bits.
+func to8BitRGBA(c color.NRGBA64) color.RGBA {
+	round := func(v uint16) uint8 {
+		return uint8((uint32(v)*255 + 0x7fff) / 0xffff)
 	}
+	return color.RGBA{R: round(c.R), G: round(c.G), B: round(c.B), A: round(c.A)}
 }
 
-// ListingPixelsOrdered iterates through an image and returns all its pixels as a slice of `color.RGBA`.
+// ListingPixelsOrdered iterates through an image and returns all its pixels as a slice of `color.RGBA`
+// holding the non premultiplied channels.
 // The function preserves the original order of the pixels in the image (row by row).
 func ListingPixelsOrdered(filePath string) ([]color.RGBA, error) {
 
@@ -117,72 +100,66 @@ func ListingPixelsOrdered(filePath string) ([]color.RGBA, error) {
 	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
 
 		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			r, g, b, a := img.At(x, y).RGBA()
-			colors = append(colors, color.RGBA{R: uint8(r), G: uint8(g), B: uint8(b), A: uint8(a)})
+			colors = append(colors, rgbaAt(img, x, y))
 		}
 
 	}
 	return colors, nil
 }
 
-// ExtractColorPalette extracts the color palette of an image and prints the RGBA values of unique colors.
-// Parameters:
-// - inputFilePath: the path to the input image file.
-// - outPutFilePath: the path to the output file (currently unused).
-func ExtractColorPalette(image image.Image, colorsPerRow, colorWidth, colorHeight, colorNum int) image.Image {
-	colors, err := ListingPixels(image)
+// ListingPixelsOrdered64 works like ListingPixelsOrdered, but keeps the full 16-bit precision
+// of the pixels, useful for 16-bit PNGs and TIFFs.
+func ListingPixelsOrdered64(filePath string) ([]color.NRGBA64, error) {
+	img, err := DecodeImage(filePath)
 	if err != nil {
-		log.Fatalln("Error while trying to read the image pixels: ", err)
-	}
-	if colorsPerRow == 0 {
-		colorsPerRow = colorsPerRowDefault
-	}
-	if colorWidth == 0 {
-		colorWidth = colorBlockWidth
-	}
-	if colorHeight == 0 {
-		colorHeight = colorBlockHeight
+		return nil, err
 	}
-	if colorNum == 0 {
-		colorNum = colorNumDefault
+
+	bounds := img.Bounds()
+	colors := make([]color.NRGBA64, 0, bounds.Dx()*bounds.Dy())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			colors = append(colors, nrgba64At(img, x, y))
+		}
 	}
+	return colors, nil
+}
 
-	uniqueColors := getUniqueColors(colors)
-	organizedColors := organizeColorsByHSL(uniqueColors[:colorNum])
+// ExtractColorPalette extracts the color palette of an image and renders it as a grid of color blocks,
+// shaped by the layout of the options. Use ExtractPalette to get the colors themselves.
+func ExtractColorPalette(image image.Image, options PaletteOptions) (image.Image, error) {
+	palette, err := ExtractPalette(image, options)
+	if err != nil {
+		return nil, fmt.Errorf("error while trying to quantize the image colors: %w", err)
+	}
 
-	if image, err = createColorPalette(organizedColors, colorsPerRow, colorWidth, colorHeight); err != nil {
-		log.Fatalln("Error wile trying to create the Collor Pallete", err)
+	if image, err = RenderPalette(palette, options.Layout); err != nil {
+		return nil, fmt.Errorf("error while trying to create the color palette: %w", err)
 	}
-	return image
+	return image, nil
 }
 
-func createColorPalette(uniqueColors []color.RGBA, colorsPerRow, colorWidth, colorHeight int) (image.Image, error) {
-	// Criar blocos de cores sequencialmente
-	colorBlocks := make([]image.Image, len(uniqueColors))
-	for i, c := range uniqueColors {
-		img := image.NewRGBA(image.Rect(0, 0, colorWidth, colorHeight))
-		draw.Draw(img, img.Bounds(), &image.Uniform{c}, image.Point{}, draw.Src)
-		colorBlocks[i] = img
-	}
-	var verticalColors []image.Image
-	horizontalColors := make([]image.Image, 0, len(colorBlocks)/colorsPerRow)
-	for _, c := range colorBlocks {
-		verticalColors = append(verticalColors, c)
-		if len(verticalColors) == colorsPerRow {
-			horizontalColor, err := concatenateImagesHorizontal(colorHeight, verticalColors...)
-			if err != nil {
-				return nil, err
+// createColorPalette draws every row of palette indexes as a row of color blocks, the image
+// being colorsPerRow blocks wide. Rows shorter than that are left transparent at the end.
+// When labels is set, every block has its label written at the bottom.
+func createColorPalette(palette Palette, rows [][]int, colorsPerRow, colorWidth, colorHeight int, labels bool) (image.Image, error) {
+	horizontalColors := make([]image.Image, 0, len(rows))
+	for _, row := range rows {
+		// Criar blocos de cores sequencialmente
+		colorBlocks := make([]image.Image, len(row))
+		for i, index := range row {
+			c := palette.Entries[index].Color
+			img := image.NewRGBA(image.Rect(0, 0, colorWidth, colorHeight))
+			draw.Draw(img, img.Bounds(), &image.Uniform{color.NRGBA(c)}, image.Point{}, draw.Src)
+			// The empty cells, like the padding of the palette, have no label
+			if labels && c.A > 0 {
+				drawLabel(img, c, labelLines(palette, index))
 			}
-			horizontalColors = append(horizontalColors, horizontalColor)
-			verticalColors = verticalColors[:0]
+			colorBlocks[i] = img
 		}
-	}
-	// Process any remaining vertical colors
-	if len(verticalColors) > 0 {
-		horizontalColor, err := concatenateImagesHorizontal(colorHeight, verticalColors...)
+		horizontalColor, err := concatenateImagesHorizontal(colorHeight, colorBlocks...)
 		if err != nil {
 			return nil, err
-
 		}
 		horizontalColors = append(horizontalColors, horizontalColor)
 	}
@@ -195,6 +172,20 @@ func createColorPalette(uniqueColors []color.RGBA, colorsPerRow, colorWidth, col
 	return img, nil
 }
 
+// gridRows splits the indexes of n colors in rows of colorsPerRow indexes, the last one
+// possibly shorter.
+func gridRows(n, colorsPerRow int) [][]int {
+	rows := make([][]int, 0, (n+colorsPerRow-1)/colorsPerRow)
+	for start := 0; start < n; start += colorsPerRow {
+		row := make([]int, 0, colorsPerRow)
+		for i := start; i < min(start+colorsPerRow, n); i++ {
+			row = append(row, i)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
 func concatenateImagesHorizontal(colorHeight int, imgs ...image.Image) (image.Image, error) {
 	if len(imgs) == 0 {
 		return nil, fmt.Errorf("no images to concatenate")
@@ -254,49 +245,36 @@ func concatenateImagesVertical(colorWidth, colorsPerRow int, imgs ...image.Image
 }
 
 // SaveImage save the image on Output file path
-func SaveImage(img image.Image, outPutFilePath string) error {
+func SaveImage(img image.Image, outPutFilePath string) (err error) {
 	file, err := os.Create(outPutFilePath)
 	if err != nil {
 		return err
 	}
 	defer func(file *os.File) {
-		err := file.Close()
-		if err != nil {
-			log.Fatalln("Error while trying to close file", err)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
 		}
 	}(file)
 
-	// Salva a imagem no formato PNG.
-	if err := png.Encode(file, img); err != nil {
-		return err
-	}
-
-	return nil
+	// Salva a imagem no formato da extensão do arquivo, PNG por padrão.
+	return EncodeImage(file, img, ImageFormatFromPath(outPutFilePath))
 }
 
-// getUniqueColors returns a slice of unique colors from the input slice.
-// It counts the frequency of each color and sorts them in descending order.
-func getUniqueColors(colors []color.RGBA) []color.RGBA {
-	// Contar quantas vezes cada cor aparece
-	colorCounts := make(map[color.RGBA]int)
-	for _, c := range colors {
-		if c != (color.RGBA{0, 0, 0, 0}) {
-			colorCounts[c]++
-		}
-	}
-
-	// Criar uma slice com as cores únicas
-	uniqueColors := make([]color.RGBA, 0, len(colorCounts))
-	for color := range colorCounts {
-		uniqueColors = append(uniqueColors, color)
+// ImageFormatFromPath returns the image format of the file extension ("jpeg", "png", "gif",
+// "bmp" or "tiff"), or "png" when the extension is not one of them.
+func ImageFormatFromPath(filePath string) string {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".jpg", ".jpeg":
+		return "jpeg"
+	case ".gif":
+		return "gif"
+	case ".bmp":
+		return "bmp"
+	case ".tif", ".tiff":
+		return "tiff"
+	default:
+		return "png"
 	}
-
-	// Ordenar as cores pela frequência (decrescente)
-	sort.Slice(uniqueColors, func(i, j int) bool {
-		return colorCounts[uniqueColors[i]] > colorCounts[uniqueColors[j]]
-	})
-
-	return uniqueColors
 }
 
 // DecodeImage open a image from a path
@@ -305,21 +283,25 @@ func DecodeImage(filePath string) (image.Image, error) {
 	if err != nil {
 		return nil, fmt.Errorf("erro ao abrir a imagem: %w", err)
 	}
-	defer func(file *os.File) {
-		err := file.Close()
-		if err != nil {
-			log.Fatalln("Error while trying to close file", err)
-		}
-	}(file)
+	// The file is only read, so an error closing it does not affect the decoded image
+	defer file.Close()
 	img, _, err := image.Decode(file)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao decodificar a imagem: %w", err)
+		return nil, fmt.Errorf("erro ao decodificar a imagem: %w", decodeError(err))
 	}
 	return img, nil
 }
 
+// decodeError marks the errors of images in an unknown format with ErrUnsupportedFormat.
+func decodeError(err error) error {
+	if errors.Is(err, image.ErrFormat) {
+		return fmt.Errorf("%w: %w", ErrUnsupportedFormat, err)
+	}
+	return err
+}
+
 // CreateImage3x3 is a temp fuction for dev tests
-func CreateImage3x3() error {
+func CreateImage3x3() (err error) {
 	// Cria uma nova imagem RGBA de tamanho 3x3.
 	img := image.NewRGBA(image.Rect(0, 0, 3, 3))
 
@@ -341,18 +323,13 @@ func CreateImage3x3() error {
 		return err
 	}
 	defer func(file *os.File) {
-		err := file.Close()
-		if err != nil {
-			log.Fatalln("Error while trying to close file", err)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
 		}
 	}(file)
 
 	// Salva a imagem no formato PNG.
-	if err := png.Encode(file, img); err != nil {
-		return err
-	}
-
-	return nil
+	return png.Encode(file, img)
 }
 
 // RGBAToHSL converts an color in RGBA space to HSL
@@ -387,14 +364,12 @@ func RGBAToHSL(c color.RGBA) (h, s, l float64) {
 func organizeColorsByHSL(colors []color.RGBA) []color.RGBA {
 	hslColors := make([]HSLColor, len(colors))
 	for i, c := range colors {
-		if c.R != 0 && c.G != 0 && c.B != 0 && c.A != 0 {
-			h, s, l := RGBAToHSL(c)
-			hslColors[i] = HSLColor{Color: c, H: h, S: s, L: l}
-		}
+		h, s, l := RGBAToHSL(c)
+		hslColors[i] = HSLColor{Color: c, H: h, S: s, L: l}
 	}
 
 	// Ordenar por tonalidade (H), saturação (S) e luminosidade (L)
-	sort.Slice(hslColors, func(i, j int) bool {
+	sort.SliceStable(hslColors, func(i, j int) bool {
 		if hslColors[i].H == hslColors[j].H {
 			return hslColors[i].L < hslColors[j].L
 		}
@@ -422,7 +397,7 @@ func BytesToImage(imgBytes []byte, fm string) (image.Image, string, error) {
 	// Tenta detectar o formato
 	_, format, err := image.DecodeConfig(imgReader)
 	if err != nil {
-		return nil, "", err
+		return nil, "", decodeError(err)
 	}
 
 	// Volta ao início do reader
@@ -455,29 +430,32 @@ func BytesToImage(imgBytes []byte, fm string) (image.Image, string, error) {
 // format: "jpeg", "png", "gif", "bmp", "tiff", "webp"
 func ImageToBytes(img image.Image, format string) ([]byte, error) {
 	var buf bytes.Buffer
-	var err error
+	if err := EncodeImage(&buf, img, format); err != nil {
+		return nil, err
+	}
 
+	return buf.Bytes(), nil
+}
+
+// EncodeImage escreve a imagem no formato especificado, PNG se o formato não for reconhecido.
+// Imagens *image.Paletted são escritas como PNG indexado (chunks PLTE e tRNS) ou GIF,
+// mantendo a ordem das cores da paleta.
+func EncodeImage(w io.Writer, img image.Image, format string) error {
 	switch format {
 	case "jpeg":
-		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90})
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: 90})
 	case "png":
-		err = png.Encode(&buf, img)
+		return png.Encode(w, img)
 	case "gif":
 		// O pacote image/gif tem funções mais complexas para GIFs animados
 		// Esta é uma implementação básica para GIFs estáticos
-		err = gif.Encode(&buf, img, &gif.Options{})
+		return gif.Encode(w, img, &gif.Options{})
 	case "bmp":
-		err = bmp.Encode(&buf, img)
+		return bmp.Encode(w, img)
 	case "tiff":
-		err = tiff.Encode(&buf, img, &tiff.Options{})
+		return tiff.Encode(w, img, &tiff.Options{})
 	default:
 		// Default para PNG se o formato não for reconhecido
-		err = png.Encode(&buf, img)
+		return png.Encode(w, img)
 	}
-
-	if err != nil {
-		return nil, err
-	}
-
-	return buf.Bytes(), nil
 }
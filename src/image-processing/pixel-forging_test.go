@@ -0,0 +1,66 @@
+package pixelforging
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"io/fs"
+	"path/filepath"
+	"testing"
+)
+
+func TestRGBAAt(t *testing.T) {
+	tests := []struct {
+		name  string
+		color color.Color
+		want  color.RGBA
+	}{
+		{"opaque", color.NRGBA{R: 10, G: 20, B: 30, A: 255}, color.RGBA{R: 10, G: 20, B: 30, A: 255}},
+		// The channels are not premultiplied by the alpha
+		{"half transparent", color.NRGBA{R: 200, G: 100, B: 50, A: 128}, color.RGBA{R: 200, G: 100, B: 50, A: 128}},
+		{"premultiplied", color.RGBA{R: 100, G: 50, B: 25, A: 128}, color.RGBA{R: 199, G: 100, B: 50, A: 128}},
+		// 16-bit channels are rounded to the nearest 8-bit value instead of truncated
+		{"16-bit rounded up", color.NRGBA64{R: 0x12ff, G: 0xff7f, B: 0x0081, A: 0xffff}, color.RGBA{R: 0x13, G: 0xff, B: 0x01, A: 255}},
+		{"16-bit rounded down", color.NRGBA64{R: 0x1280, G: 0x0001, B: 0xfe7f, A: 0xffff}, color.RGBA{R: 0x12, G: 0x00, B: 0xfe, A: 255}},
+		{"gray", color.Gray{Y: 77}, color.RGBA{R: 77, G: 77, B: 77, A: 255}},
+		{"transparent", color.NRGBA{R: 200}, color.RGBA{}},
+	}
+	for _, tt := range tests {
+		img := image.NewNRGBA64(image.Rect(0, 0, 1, 1))
+		img.Set(0, 0, tt.color)
+		if got := rgbaAt(img, 0, 0); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLibraryErrors(t *testing.T) {
+	transparent := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	tests := []struct {
+		name    string
+		run     func() error
+		wantErr error
+	}{
+		{"unknown image format", func() error {
+			_, _, err := BytesToImage([]byte("not an image"), "")
+			return err
+		}, ErrUnsupportedFormat},
+		{"missing file", func() error {
+			_, err := DecodeImage(filepath.Join(t.TempDir(), "missing.png"))
+			return err
+		}, fs.ErrNotExist},
+		{"transparent image", func() error {
+			_, err := ExtractColorPalette(transparent, PaletteOptions{})
+			return err
+		}, ErrTooFewColors},
+		{"unknown algorithm", func() error {
+			_, err := ExtractColorPalette(randomImage(2, 2, 1), PaletteOptions{Algorithm: "popularity"})
+			return err
+		}, ErrInvalidOption},
+	}
+	for _, tt := range tests {
+		if err := tt.run(); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
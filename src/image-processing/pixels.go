@@ -0,0 +1,120 @@
+package pixelforging
+
+import (
+	"image"
+	"image/color"
+	"runtime"
+	"sync"
+)
+
+// rowReader fills row with the pixels of the line y of an image, as rgbaAt reads them.
+type rowReader func(y int, row []color.RGBA)
+
+// newRowReader returns a rowReader for the image. The common image types are read straight
+// from their Pix slices, the others pixel by pixel through rgbaAt. A rowReader can be used
+// by many goroutines at once.
+func newRowReader(img image.Image) rowReader {
+	bounds := img.Bounds()
+	switch m := img.(type) {
+	case *image.NRGBA:
+		return func(y int, row []color.RGBA) {
+			pix := m.Pix[m.PixOffset(bounds.Min.X, y):]
+			for i := range row {
+				p := pix[4*i : 4*i+4 : 4*i+4]
+				row[i] = color.RGBA{R: p[0], G: p[1], B: p[2], A: p[3]}
+			}
+		}
+	case *image.RGBA:
+		return func(y int, row []color.RGBA) {
+			pix := m.Pix[m.PixOffset(bounds.Min.X, y):]
+			for i := range row {
+				p := pix[4*i : 4*i+4 : 4*i+4]
+				row[i] = unpremultiply(color.RGBA{R: p[0], G: p[1], B: p[2], A: p[3]})
+			}
+		}
+	case *image.Paletted:
+		colors := make([]color.RGBA, max(256, len(m.Palette)))
+		for i, c := range m.Palette {
+			colors[i] = to8BitRGBA(toNRGBA64(c))
+		}
+		return func(y int, row []color.RGBA) {
+			pix := m.Pix[m.PixOffset(bounds.Min.X, y):]
+			for i := range row {
+				row[i] = colors[pix[i]]
+			}
+		}
+	case *image.Gray:
+		return func(y int, row []color.RGBA) {
+			pix := m.Pix[m.PixOffset(bounds.Min.X, y):]
+			for i := range row {
+				row[i] = color.RGBA{R: pix[i], G: pix[i], B: pix[i], A: 255}
+			}
+		}
+	case *image.YCbCr:
+		return func(y int, row []color.RGBA) {
+			for i := range row {
+				r, g, b, _ := m.YCbCrAt(bounds.Min.X+i, y).RGBA()
+				row[i] = to8BitRGBA(color.NRGBA64{R: uint16(r), G: uint16(g), B: uint16(b), A: 0xffff})
+			}
+		}
+	case alphaImage:
+		read := newRowReader(m.Image)
+		return func(y int, row []color.RGBA) {
+			read(y, row)
+			for i, c := range row {
+				row[i] = m.apply(c)
+			}
+		}
+	default:
+		return func(y int, row []color.RGBA) {
+			for i := range row {
+				row[i] = rgbaAt(img, bounds.Min.X+i, y)
+			}
+		}
+	}
+}
+
+// unpremultiply converts a premultiplied color to the non premultiplied channels, rounding
+// like rgbaAt.
+func unpremultiply(c color.RGBA) color.RGBA {
+	switch c.A {
+	case 255:
+		return c
+	case 0:
+		return color.RGBA{}
+	default:
+		return to8BitRGBA(color.NRGBA64Model.Convert(c).(color.NRGBA64))
+	}
+}
+
+// scanRows reads the image in bands of consecutive rows, one band per worker. Each band gets
+// its own state from newState, and visit is called with it for every row of the band, in
+// order. The states are returned in the order of the bands, so merging them in that order
+// gives the same result on every run.
+func scanRows[S any](img image.Image, newState func() S, visit func(state S, y int, row []color.RGBA)) []S {
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return nil
+	}
+	bandRows := (bounds.Dy() + runtime.GOMAXPROCS(0) - 1) / runtime.GOMAXPROCS(0)
+	states := make([]S, (bounds.Dy()+bandRows-1)/bandRows)
+	read := newRowReader(img)
+
+	var wg sync.WaitGroup
+	for band := range states {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			state := newState()
+			row := make([]color.RGBA, bounds.Dx())
+			start := bounds.Min.Y + band*bandRows
+			for y := start; y < min(start+bandRows, bounds.Max.Y); y++ {
+				read(y, row)
+				visit(state, y, row)
+			}
+			states[band] = state
+		}()
+	}
+	wg.Wait()
+	return states
+}
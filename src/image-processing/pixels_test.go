@@ -0,0 +1,139 @@
+package pixelforging
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// genericImage hides the type of an image, so it is read through rgbaAt.
+type genericImage struct {
+	image.Image
+}
+
+// randomNRGBA returns a random color, a quarter of them fully opaque and some fully transparent.
+func randomNRGBA(rng *rand.Rand) color.NRGBA {
+	c := color.NRGBA{R: uint8(rng.Intn(256)), G: uint8(rng.Intn(256)), B: uint8(rng.Intn(256)), A: uint8(rng.Intn(256))}
+	switch rng.Intn(8) {
+	case 0, 1:
+		c.A = 255
+	case 2:
+		c.A = 0
+	}
+	return c
+}
+
+// fillRandom sets every pixel of the image to a random color.
+func fillRandom(img interface {
+	image.Image
+	Set(x, y int, c color.Color)
+}, rng *rand.Rand) {
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			img.Set(x, y, randomNRGBA(rng))
+		}
+	}
+}
+
+// rowReaderImages are images of every type newRowReader reads from their Pix slices, with
+// bounds that do not start at the origin.
+func rowReaderImages() map[string]image.Image {
+	rng := rand.New(rand.NewSource(11))
+	rect := image.Rect(-3, 2, 14, 11)
+	images := make(map[string]image.Image)
+
+	nrgba := image.NewNRGBA(rect)
+	fillRandom(nrgba, rng)
+	images["NRGBA"] = nrgba
+	rgba := image.NewRGBA(rect)
+	fillRandom(rgba, rng)
+	images["RGBA"] = rgba
+	gray := image.NewGray(rect)
+	fillRandom(gray, rng)
+	images["Gray"] = gray
+	nrgba64 := image.NewNRGBA64(rect)
+	fillRandom(nrgba64, rng)
+	images["NRGBA64"] = nrgba64
+
+	palette := make(color.Palette, 40)
+	for i := range palette {
+		palette[i] = randomNRGBA(rng)
+	}
+	paletted := image.NewPaletted(rect, palette)
+	for i := range paletted.Pix {
+		paletted.Pix[i] = uint8(rng.Intn(len(palette)))
+	}
+	images["Paletted"] = paletted
+
+	for _, ratio := range []image.YCbCrSubsampleRatio{image.YCbCrSubsampleRatio444, image.YCbCrSubsampleRatio420} {
+		ycbcr := image.NewYCbCr(rect, ratio)
+		for _, channel := range [][]uint8{ycbcr.Y, ycbcr.Cb, ycbcr.Cr} {
+			rng.Read(channel)
+		}
+		images["YCbCr "+ratio.String()] = ycbcr
+	}
+
+	images["alpha ignore"] = alphaImage{Image: nrgba, policy: AlphaIgnore, threshold: 128}
+	images["alpha premultiply"] = alphaImage{Image: rgba, policy: AlphaPremultiply}
+	// A sub image starts its rows in the middle of the Pix slice
+	images["NRGBA sub image"] = nrgba.SubImage(image.Rect(1, 4, 9, 10))
+	return images
+}
+
+func TestRowReaderMatchesRGBAAt(t *testing.T) {
+	for name, img := range rowReaderImages() {
+		b := img.Bounds()
+		fast, generic := newRowReader(img), newRowReader(genericImage{img})
+		row, genericRow := make([]color.RGBA, b.Dx()), make([]color.RGBA, b.Dx())
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			fast(y, row)
+			generic(y, genericRow)
+			for i, c := range row {
+				if want := rgbaAt(img, b.Min.X+i, y); c != want || genericRow[i] != want {
+					t.Errorf("%s, pixel (%d, %d): read %v and %v, want %v", name, b.Min.X+i, y, c, genericRow[i], want)
+				}
+			}
+		}
+	}
+}
+
+func TestScanRows(t *testing.T) {
+	img := randomImage(5, 37, 12)
+	var mu sync.Mutex
+	visited := make(map[int]int)
+	states := scanRows(img, func() *[]int { return new([]int) }, func(rows *[]int, y int, row []color.RGBA) {
+		if len(row) != 5 || row[2] != img.RGBAAt(2, y) {
+			t.Errorf("row %d was read wrong", y)
+		}
+		*rows = append(*rows, y)
+		mu.Lock()
+		visited[y]++
+		mu.Unlock()
+	})
+
+	// Merging the states in order visits the rows in order
+	var rows []int
+	for _, s := range states {
+		rows = append(rows, *s...)
+	}
+	want := make([]int, 37)
+	for y := range want {
+		want[y] = y
+	}
+	if !slices.Equal(rows, want) {
+		t.Errorf("the bands visited the rows %v, want %v", rows, want)
+	}
+	for y, n := range visited {
+		if n != 1 {
+			t.Errorf("row %d was visited %d times", y, n)
+		}
+	}
+
+	if states := scanRows(image.NewRGBA(image.Rectangle{}), func() int { return 0 }, func(int, int, []color.RGBA) {}); states != nil {
+		t.Errorf("an empty image returned %d states", len(states))
+	}
+}
@@ -0,0 +1,147 @@
+package pixelforging
+
+import (
+	"fmt"
+	"image/color"
+	"sort"
+)
+
+// Names of the palette extraction algorithms accepted by NewQuantizer.
+const (
+	AlgorithmFrequency = "frequency"
+	AlgorithmMedianCut = "median-cut"
+	AlgorithmKMeans    = "kmeans"
+	AlgorithmOctree    = "octree"
+	AlgorithmWu        = "wu"
+)
+
+// ColorCount is a palette color together with the number of pixels of the image it represents.
+// The color holds the non premultiplied channels, see PaletteEntry.Color.
+type ColorCount struct {
+	Color color.RGBA
+	Count int
+}
+
+// Quantizer reduces the colors counted by a histogram to a palette of at most colorNum
+// colors. The returned colors are sorted by the number of pixels they represent (descending).
+// 0 colors gives an empty palette and a negative number an error wrapping ErrInvalidOption.
+type Quantizer interface {
+	Quantize(histogram *Histogram, colorNum int) ([]ColorCount, error)
+}
+
+// checkColorNum returns the error of a negative number of colors asked to a quantizer, the
+// quantizers return an empty palette when asked for 0 colors.
+func checkColorNum(colorNum int) error {
+	if colorNum < 0 {
+		return fmt.Errorf("%w: the number of colors can not be negative, got %d", ErrInvalidOption, colorNum)
+	}
+	return nil
+}
+
+// QuantizerOptions holds the settings of the algorithms that accept any.
+// Settings that do not apply to the selected algorithm are ignored.
+type QuantizerOptions struct {
+	// Seed of the k-means initialization, 0 picks a random seed.
+	Seed int64
+	// Depth of the octree, from 1 to 8, 0 uses the maximum depth.
+	OctreeDepth int
+	// MergeThreshold is the Delta-E under which the frequency algorithm merges two colors, 0 disables it.
+	MergeThreshold float64
+	// DeltaE is the formula used to compare colors, empty uses CIEDE2000.
+	DeltaE DeltaEFormula
+}
+
+// NewQuantizer returns the Quantizer registered with the given algorithm name.
+// An empty name selects the frequency algorithm, that was the only one available before.
+func NewQuantizer(algorithm string, options QuantizerOptions) (Quantizer, error) {
+	if options.MergeThreshold < 0 {
+		return nil, fmt.Errorf("%w: merge threshold can not be negative, got %v", ErrInvalidOption, options.MergeThreshold)
+	}
+	formula, err := ParseDeltaEFormula(string(options.DeltaE))
+	if err != nil {
+		return nil, err
+	}
+
+	switch algorithm {
+	case "", AlgorithmFrequency:
+		return FrequencyQuantizer{MergeThreshold: options.MergeThreshold, MergeFormula: formula}, nil
+	case AlgorithmMedianCut:
+		return MedianCutQuantizer{}, nil
+	case AlgorithmKMeans:
+		return KMeansQuantizer{Seed: options.Seed}, nil
+	case AlgorithmOctree:
+		if options.OctreeDepth < 0 || options.OctreeDepth > octreeMaxDepth {
+			return nil, fmt.Errorf("%w: octree depth must be between 1 and %d, got %d", ErrInvalidOption, octreeMaxDepth, options.OctreeDepth)
+		}
+		return OctreeQuantizer{Depth: options.OctreeDepth}, nil
+	case AlgorithmWu:
+		return WuQuantizer{}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown palette algorithm %q", ErrInvalidOption, algorithm)
+	}
+}
+
+// FrequencyQuantizer picks the colorNum most frequent exact RGBA values of the image.
+// It is the best choice for pixel art, where every color is placed on purpose.
+type FrequencyQuantizer struct {
+	// MergeThreshold is the Delta-E under which a color is merged into a more frequent one
+	// before the most frequent colors are picked, so the palette does not waste slots on
+	// colors the eye can not tell apart. 0 disables the merge.
+	MergeThreshold float64
+	// MergeFormula is the Delta-E formula used by the merge.
+	MergeFormula DeltaEFormula
+}
+
+// Quantize implements Quantizer.
+func (q FrequencyQuantizer) Quantize(histogram *Histogram, colorNum int) ([]ColorCount, error) {
+	if colorNum <= 0 {
+		return nil, checkColorNum(colorNum)
+	}
+	palette := mergeSimilarColors(histogram.Colors(), q.MergeThreshold, q.MergeFormula)
+	if len(palette) > colorNum {
+		palette = palette[:colorNum]
+	}
+	return palette, nil
+}
+
+// sortColorCounts sorts the palette by pixel count (descending). Ties are broken by the
+// color value so the result does not depend on map iteration order.
+func sortColorCounts(palette []ColorCount) {
+	sort.Slice(palette, func(i, j int) bool {
+		if palette[i].Count != palette[j].Count {
+			return palette[i].Count > palette[j].Count
+		}
+		return colorKey(palette[i].Color) < colorKey(palette[j].Color)
+	})
+}
+
+func colorKey(c color.RGBA) uint32 {
+	return uint32(c.R)<<24 | uint32(c.G)<<16 | uint32(c.B)<<8 | uint32(c.A)
+}
+
+// mergeDuplicateColors merges the entries of the palette that have the same color, adding
+// their counts, and sorts the result by pixel count. Averaging quantizers, like median-cut
+// and octree, can round two boxes of the histogram to the same color.
+func mergeDuplicateColors(palette []ColorCount) []ColorCount {
+	indexes := make(map[color.RGBA]int, len(palette))
+	merged := make([]ColorCount, 0, len(palette))
+	for _, c := range palette {
+		if i, ok := indexes[c.Color]; ok {
+			merged[i].Count += c.Count
+			continue
+		}
+		indexes[c.Color] = len(merged)
+		merged = append(merged, c)
+	}
+	sortColorCounts(merged)
+	return merged
+}
+
+// colorsOf returns only the colors of the palette, in the same order.
+func colorsOf(palette []ColorCount) []color.RGBA {
+	colors := make([]color.RGBA, len(palette))
+	for i, c := range palette {
+		colors[i] = c.Color
+	}
+	return colors
+}
@@ -0,0 +1,97 @@
+package pixelforging
+
+import (
+	"errors"
+	"image/color"
+	"testing"
+)
+
+// newTestHistogram returns an exact colors histogram that counts the colors.
+func newTestHistogram(t *testing.T, colors ...ColorCount) *Histogram {
+	t.Helper()
+	histogram, err := NewHistogram(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range colors {
+		histogram.Add(c.Color, c.Count)
+	}
+	return histogram
+}
+
+func TestNewQuantizer(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		options   QuantizerOptions
+		want      Quantizer
+		wantErr   error
+	}{
+		{"", QuantizerOptions{}, FrequencyQuantizer{MergeFormula: DeltaE2000}, nil},
+		{AlgorithmFrequency, QuantizerOptions{MergeThreshold: 3, DeltaE: DeltaE76}, FrequencyQuantizer{MergeThreshold: 3, MergeFormula: DeltaE76}, nil},
+		{AlgorithmMedianCut, QuantizerOptions{}, MedianCutQuantizer{}, nil},
+		{AlgorithmKMeans, QuantizerOptions{Seed: 7}, KMeansQuantizer{Seed: 7}, nil},
+		{AlgorithmOctree, QuantizerOptions{OctreeDepth: 4}, OctreeQuantizer{Depth: 4}, nil},
+		{AlgorithmWu, QuantizerOptions{}, WuQuantizer{}, nil},
+		{"popularity", QuantizerOptions{}, nil, ErrInvalidOption},
+		{AlgorithmFrequency, QuantizerOptions{MergeThreshold: -1}, nil, ErrInvalidOption},
+		{AlgorithmOctree, QuantizerOptions{OctreeDepth: 9}, nil, ErrInvalidOption},
+		{AlgorithmFrequency, QuantizerOptions{DeltaE: "cie2020"}, nil, ErrInvalidOption},
+	}
+	for _, tt := range tests {
+		got, err := NewQuantizer(tt.algorithm, tt.options)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%q %+v: got error %v, want %v", tt.algorithm, tt.options, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q %+v: got %#v, want %#v", tt.algorithm, tt.options, got, tt.want)
+		}
+	}
+}
+
+func TestFrequencyQuantizer(t *testing.T) {
+	red, green, blue := color.RGBA{R: 255, A: 255}, color.RGBA{G: 255, A: 255}, color.RGBA{B: 255, A: 255}
+	histogram := newTestHistogram(t, ColorCount{green, 2}, ColorCount{red, 5}, ColorCount{blue, 3})
+	tests := []struct {
+		colorNum int
+		want     []ColorCount
+	}{
+		{1, []ColorCount{{red, 5}}},
+		{2, []ColorCount{{red, 5}, {blue, 3}}},
+		{5, []ColorCount{{red, 5}, {blue, 3}, {green, 2}}},
+	}
+	for _, tt := range tests {
+		got, err := FrequencyQuantizer{}.Quantize(histogram, tt.colorNum)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(tt.want) {
+			t.Fatalf("%d colors: got %v, want %v", tt.colorNum, got, tt.want)
+		}
+		for i := range tt.want {
+			if got[i] != tt.want[i] {
+				t.Errorf("%d colors: got %v, want %v", tt.colorNum, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestQuantizeColorNum(t *testing.T) {
+	histogram := newTestHistogram(t, spreadColors()...)
+	quantizers := []Quantizer{FrequencyQuantizer{}, MedianCutQuantizer{}, KMeansQuantizer{Seed: 1}, OctreeQuantizer{}, WuQuantizer{}}
+	for _, q := range quantizers {
+		// No color gives an empty palette and a negative number an error
+		palette, err := q.Quantize(histogram, 0)
+		if err != nil || len(palette) != 0 {
+			t.Errorf("%T, 0 colors: got %v, %v, want an empty palette", q, palette, err)
+		}
+		if _, err := q.Quantize(histogram, -1); !errors.Is(err, ErrInvalidOption) {
+			t.Errorf("%T, -1 colors: got %v, want ErrInvalidOption", q, err)
+		}
+		palette, err = q.Quantize(histogram, 1)
+		if err != nil || len(palette) != 1 {
+			t.Errorf("%T, 1 color: got %v, %v, want 1 color", q, palette, err)
+		}
+	}
+}
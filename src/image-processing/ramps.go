@@ -0,0 +1,131 @@
+package pixelforging
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// rampHueGap is the hue distance, in degrees, between two colors that starts a new ramp.
+	rampHueGap = 30
+	// rampMaxHueSpan is the widest hue range, in degrees, of a ramp. Pixel art ramps shift
+	// their hue from the shadow to the highlight, but a whole rainbow is not a ramp.
+	rampMaxHueSpan = 90
+	// mutedChroma is the CIELAB chroma under which a color belongs to the muted ramps, like
+	// the steel blues and browns that pixel art palettes keep apart from their vivid ramps.
+	mutedChroma = 20
+)
+
+// hueFamilyNames are the names of the hue families, every 30 degrees starting at red.
+var hueFamilyNames = [hueFamilies]string{
+	"red", "orange", "yellow", "chartreuse", "green", "spring green",
+	"cyan", "azure", "blue", "violet", "magenta", "rose",
+}
+
+// Ramp is a group of palette colors of similar hue, ordered from the shadow to the
+// highlight, the way pixel art palettes are organized.
+type Ramp struct {
+	// Name is the hue family of the most frequent color of the ramp, like "red" or "gray".
+	// Palettes read from files have no pixel counts and are named by their darkest color.
+	Name string
+	// Entries are the colors of the ramp, from the darkest to the lightest.
+	Entries []PaletteEntry
+	// Indexes are the positions of the entries in the palette.
+	Indexes []int
+}
+
+// Ramps groups the colors of the palette into hue ramps. The grays make the first ramp. The
+// vivid colors, and then the muted ones, are walked around the hue circle, a new ramp
+// starting at every gap wider than 30 degrees or when the ramp would span more than 90
+// degrees, so the gradual hue shift of a ramp keeps it whole. The ramps follow the hue
+// circle starting at red, the vivid ramp of a hue before the muted one, and each ramp is
+// ordered by CIELAB lightness. Fully transparent entries, like the padding of ShortagePad,
+// belong to no ramp.
+func (p Palette) Ramps() []Ramp {
+	var grays, muted, vivid []int
+	for i, e := range p.Entries {
+		switch chroma := math.Hypot(e.Lab.A, e.Lab.B); {
+		case e.Color.A == 0:
+		case chroma < neutralChroma:
+			grays = append(grays, i)
+		case chroma < mutedChroma:
+			muted = append(muted, i)
+		default:
+			vivid = append(vivid, i)
+		}
+	}
+
+	groups := append(p.splitByHue(vivid), p.splitByHue(muted)...)
+	sort.SliceStable(groups, func(i, j int) bool {
+		return hueFamily(p.Entries[groups[i][0]].Color) < hueFamily(p.Entries[groups[j][0]].Color)
+	})
+	if len(grays) > 0 {
+		groups = append([][]int{grays}, groups...)
+	}
+	ramps := make([]Ramp, len(groups))
+	for i, indexes := range groups {
+		ramps[i] = p.newRamp(indexes)
+	}
+	return ramps
+}
+
+// splitByHue splits the indexes of chromatic entries into groups of similar hue, each one
+// starting at its lowest hue.
+func (p Palette) splitByHue(indexes []int) [][]int {
+	if len(indexes) == 0 {
+		return nil
+	}
+	hue := func(i int) float64 { return p.Entries[i].HSL.H }
+	sort.SliceStable(indexes, func(i, j int) bool { return hue(indexes[i]) < hue(indexes[j]) })
+
+	// The walk starts after the widest gap of the circle, so no ramp is cut at 0 degrees
+	gap := func(i int) float64 {
+		next := (i + 1) % len(indexes)
+		return math.Mod(hue(indexes[next])-hue(indexes[i])+360, 360)
+	}
+	widest := len(indexes) - 1
+	for i := range indexes {
+		if gap(i) > gap(widest) {
+			widest = i
+		}
+	}
+
+	var groups [][]int
+	var group []int
+	span := 0.0
+	for n := range indexes {
+		i := (widest + 1 + n) % len(indexes)
+		if len(group) > 0 {
+			g := gap((i - 1 + len(indexes)) % len(indexes))
+			if g > rampHueGap || span+g > rampMaxHueSpan {
+				groups = append(groups, group)
+				group, span = nil, 0
+			} else {
+				span += g
+			}
+		}
+		group = append(group, indexes[i])
+	}
+	return append(groups, group)
+}
+
+// newRamp builds the ramp of the entries at the indexes, ordered by lightness.
+func (p Palette) newRamp(indexes []int) Ramp {
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return p.Entries[indexes[i]].Lab.L < p.Entries[indexes[j]].Lab.L
+	})
+	ramp := Ramp{Indexes: indexes}
+	base := p.Entries[indexes[0]]
+	for _, i := range indexes {
+		ramp.Entries = append(ramp.Entries, p.Entries[i])
+		if p.Entries[i].Count > base.Count {
+			base = p.Entries[i]
+		}
+	}
+	if family := hueFamily(base.Color); family == 0 {
+		ramp.Name = "gray"
+	} else {
+		ramp.Name = hueFamilyNames[family-1]
+	}
+	return ramp
+}
@@ -0,0 +1,124 @@
+package pixelforging
+
+import (
+	"errors"
+	"image/color"
+	"slices"
+	"testing"
+)
+
+// rampPalette builds a palette of the colors, each one covering count pixels.
+func rampPalette(colors []color.RGBA, counts []int) Palette {
+	palette := Palette{}
+	for _, n := range counts {
+		palette.TotalPixels += n
+	}
+	for i, c := range colors {
+		palette.Entries = append(palette.Entries, NewPaletteEntry(c, counts[i], palette.TotalPixels))
+	}
+	return palette
+}
+
+func TestPaletteRamps(t *testing.T) {
+	tests := []struct {
+		name        string
+		colors      []color.RGBA
+		counts      []int
+		wantNames   []string
+		wantIndexes [][]int
+	}{
+		{
+			"grays first, then the hue circle from red",
+			[]color.RGBA{
+				{R: 40, G: 60, B: 200, A: 255},   // 0 blue
+				{R: 255, G: 255, B: 255, A: 255}, // 1 white
+				{R: 220, G: 40, B: 40, A: 255},   // 2 red
+				{R: 110, G: 10, B: 20, A: 255},   // 3 dark red
+				{A: 255},                         // 4 black
+				{R: 20, G: 30, B: 110, A: 255},   // 5 dark blue
+				{},                               // 6 padding
+			},
+			[]int{5, 1, 2, 9, 1, 1, 0},
+			[]string{"gray", "red", "blue"},
+			[][]int{{4, 1}, {3, 2}, {5, 0}},
+		},
+		{
+			// Red wraps around 0 degrees, the shadows shift to rose and the highlights to orange
+			"ramp across 0 degrees",
+			[]color.RGBA{
+				{R: 255, G: 140, B: 60, A: 255}, // 0 orange highlight
+				{R: 120, G: 20, B: 60, A: 255},  // 1 rose shadow
+				{R: 200, G: 40, B: 30, A: 255},  // 2 red
+			},
+			[]int{1, 1, 3},
+			[]string{"red"},
+			[][]int{{1, 2, 0}},
+		},
+		{
+			// Every gap is 25 degrees, the ramp stops before spanning more than 90 degrees
+			"a rainbow is split",
+			[]color.RGBA{
+				{R: 255, A: 255},         // 0 red, 0 degrees
+				{R: 255, G: 106, A: 255}, // 1 25 degrees
+				{R: 255, G: 212, A: 255}, // 2 50 degrees
+				{R: 191, G: 255, A: 255}, // 3 75 degrees
+				{R: 85, G: 255, A: 255},  // 4 100 degrees
+			},
+			[]int{1, 1, 1, 1, 1},
+			[]string{"red", "chartreuse"},
+			[][]int{{0, 1, 2, 3}, {4}},
+		},
+		{"no visible colors", []color.RGBA{{}, {}}, []int{0, 0}, nil, nil},
+	}
+	for _, tt := range tests {
+		ramps := rampPalette(tt.colors, tt.counts).Ramps()
+		var names []string
+		var indexes [][]int
+		for _, r := range ramps {
+			names = append(names, r.Name)
+			indexes = append(indexes, r.Indexes)
+			for i, e := range r.Entries {
+				if e.Color != tt.colors[r.Indexes[i]] {
+					t.Errorf("%s: entry %d of the %s ramp is %v, want the color %d", tt.name, i, r.Name, e.Color, r.Indexes[i])
+				}
+			}
+		}
+		if !slices.Equal(names, tt.wantNames) || !slices.EqualFunc(indexes, tt.wantIndexes, slices.Equal[[]int]) {
+			t.Errorf("%s: got ramps %v %v, want %v %v", tt.name, names, indexes, tt.wantNames, tt.wantIndexes)
+		}
+	}
+}
+
+func TestRenderPaletteRamps(t *testing.T) {
+	palette := rampPalette([]color.RGBA{
+		{R: 220, G: 40, B: 40, A: 255},
+		{A: 255},
+		{R: 110, G: 10, B: 20, A: 255},
+		{R: 255, G: 255, B: 255, A: 255},
+		{R: 120, G: 120, B: 120, A: 255},
+	}, []int{1, 1, 1, 1, 1})
+	img, err := RenderPalette(palette, PaletteLayout{Mode: LayoutRamps, ColorWidth: 2, ColorHeight: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// One row per ramp, as wide as the longest ramp, the grays first
+	want := [][]color.RGBA{
+		{palette.Entries[1].Color, palette.Entries[4].Color, palette.Entries[3].Color},
+		{palette.Entries[2].Color, palette.Entries[0].Color, {}},
+	}
+	if b := img.Bounds(); b.Dx() != 6 || b.Dy() != 4 {
+		t.Fatalf("got a %dx%d image, want 6x4", b.Dx(), b.Dy())
+	}
+	for row, colors := range want {
+		for i, c := range colors {
+			if got := rgbaAt(img, 2*i+1, 2*row+1); got != c {
+				t.Errorf("swatch %d of row %d is %v, want %v", i, row, got, c)
+			}
+		}
+	}
+
+	transparent := rampPalette([]color.RGBA{{}}, []int{0})
+	if _, err := RenderPalette(transparent, PaletteLayout{Mode: LayoutRamps}); !errors.Is(err, ErrTooFewColors) {
+		t.Errorf("a palette without visible colors: got %v, want ErrTooFewColors", err)
+	}
+}
@@ -0,0 +1,169 @@
+package pixelforging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sync"
+)
+
+// DistanceMetric selects how the distance between a pixel and the palette colors is measured.
+type DistanceMetric string
+
+// Supported distance metrics.
+const (
+	// MetricRGB is the euclidean distance between the RGB channels, the fastest one.
+	MetricRGB DistanceMetric = "rgb"
+	// MetricWeightedRGB weights the RGB channels by how sensitive the eye is to each one
+	// ("redmean" approximation), much closer to the perceived difference at almost no cost.
+	MetricWeightedRGB DistanceMetric = "weighted-rgb"
+	// MetricCIE76, MetricCIE94 and MetricCIEDE2000 measure the Delta-E between the colors
+	// in CIELAB with the matching formula, the most accurate and slowest options.
+	MetricCIE76     DistanceMetric = DistanceMetric(DeltaE76)
+	MetricCIE94     DistanceMetric = DistanceMetric(DeltaE94)
+	MetricCIEDE2000 DistanceMetric = DistanceMetric(DeltaE2000)
+)
+
+// ParseDistanceMetric validates a distance metric name. An empty name selects MetricWeightedRGB.
+func ParseDistanceMetric(name string) (DistanceMetric, error) {
+	switch metric := DistanceMetric(name); metric {
+	case "":
+		return MetricWeightedRGB, nil
+	case MetricRGB, MetricWeightedRGB, MetricCIE76, MetricCIE94, MetricCIEDE2000:
+		return metric, nil
+	default:
+		return "", fmt.Errorf("%w: unknown distance metric %q", ErrInvalidOption, name)
+	}
+}
+
+// remapCacheSize bounds the colors cached by each worker of RemapImage, so pictures
+// with millions of colors do not use unbounded memory.
+const remapCacheSize = 1 << 16
+
+// colorMatcher finds the palette color nearest to a color with a distance metric.
+type colorMatcher struct {
+	colors []color.RGBA
+	labs   []LabColor
+	metric DistanceMetric
+}
+
+func newColorMatcher(colors []color.RGBA, metric DistanceMetric) *colorMatcher {
+	m := &colorMatcher{colors: colors, metric: metric}
+	if m.usesLab() {
+		m.labs = make([]LabColor, len(colors))
+		for i, c := range colors {
+			m.labs[i] = RGBAToLab(c)
+		}
+	}
+	return m
+}
+
+func (m *colorMatcher) usesLab() bool {
+	return m.metric != MetricRGB && m.metric != MetricWeightedRGB
+}
+
+// nearest returns the index of the palette color nearest to c.
+func (m *colorMatcher) nearest(c color.RGBA) int {
+	var lab LabColor
+	if m.usesLab() {
+		lab = RGBAToLab(c)
+	}
+
+	nearest, nearestDistance := 0, math.Inf(1)
+	for i, p := range m.colors {
+		var d float64
+		switch m.metric {
+		case MetricRGB:
+			d = distanceRGB(c, p)
+		case MetricWeightedRGB:
+			d = distanceWeightedRGB(c, p)
+		default:
+			d = DeltaE(DeltaEFormula(m.metric), lab, m.labs[i])
+		}
+		if d < nearestDistance {
+			nearest, nearestDistance = i, d
+		}
+	}
+	return nearest
+}
+
+// distanceRGB returns the squared euclidean distance between the RGB channels.
+func distanceRGB(a, b color.RGBA) float64 {
+	dr := float64(a.R) - float64(b.R)
+	dg := float64(a.G) - float64(b.G)
+	db := float64(a.B) - float64(b.B)
+	return dr*dr + dg*dg + db*db
+}
+
+// distanceWeightedRGB returns the squared "redmean" distance, that weights the red and
+// blue channels by the mean red of the colors.
+func distanceWeightedRGB(a, b color.RGBA) float64 {
+	rMean := (float64(a.R) + float64(b.R)) / 2
+	dr := float64(a.R) - float64(b.R)
+	dg := float64(a.G) - float64(b.G)
+	db := float64(a.B) - float64(b.B)
+	return (2+rMean/256)*dr*dr + 4*dg*dg + (2+(255-rMean)/256)*db*db
+}
+
+// RemapImage replaces every pixel of the image with the nearest color of the palette,
+// measured with the metric. Fully transparent pixels are kept transparent.
+func RemapImage(img image.Image, palette Palette, metric DistanceMetric) (*image.NRGBA, error) {
+	if len(palette.Entries) == 0 {
+		return nil, fmt.Errorf("%w: the palette has no colors", ErrTooFewColors)
+	}
+	return mapToColors(img, palette.Colors(), metric, nil), nil
+}
+
+// mapToColors replaces every visible pixel of the image with the color nearest to it.
+// When adjust is not nil, the pixel is first replaced by adjust(x, y, pixel), that must
+// not depend on the other pixels. The lines of the image are processed in parallel,
+// like in ListingPixels.
+func mapToColors(img image.Image, colors []color.RGBA, metric DistanceMetric, adjust func(x, y int, c color.RGBA) color.RGBA) *image.NRGBA {
+	bounds := img.Bounds()
+	out := image.NewNRGBA(bounds)
+	read := newRowReader(img)
+
+	pools := min(bounds.Dy(), 32)
+	linesToProcess := make(chan int, pools)
+	var wg sync.WaitGroup
+	for i := 0; i < pools; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Each worker keeps its own cache, images repeat the same colors a lot
+			matcher := newColorMatcher(colors, metric)
+			cache := make(map[color.RGBA]color.RGBA)
+			row := make([]color.RGBA, bounds.Dx())
+			for y := range linesToProcess {
+				read(y, row)
+				for i, c := range row {
+					x := bounds.Min.X + i
+					if c.A == 0 {
+						continue
+					}
+					if adjust != nil {
+						c = adjust(x, y, c)
+					}
+					mapped, ok := cache[c]
+					if !ok {
+						if len(cache) == remapCacheSize {
+							clear(cache)
+						}
+						mapped = colors[matcher.nearest(c)]
+						cache[c] = mapped
+					}
+					out.SetNRGBA(x, y, color.NRGBA(mapped))
+				}
+			}
+		}()
+	}
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		linesToProcess <- y
+	}
+	close(linesToProcess)
+	wg.Wait()
+
+	return out
+}
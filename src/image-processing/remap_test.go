@@ -0,0 +1,99 @@
+package pixelforging
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestParseDistanceMetric(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    DistanceMetric
+		wantErr error
+	}{
+		{"", MetricWeightedRGB, nil},
+		{"rgb", MetricRGB, nil},
+		{"ciede2000", MetricCIEDE2000, nil},
+		{"manhattan", "", ErrInvalidOption},
+	}
+	for _, tt := range tests {
+		got, err := ParseDistanceMetric(tt.name)
+		if got != tt.want || !errors.Is(err, tt.wantErr) {
+			t.Errorf("%q: got %q, %v, want %q, %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestColorMatcherNearest(t *testing.T) {
+	colors := []color.RGBA{
+		{A: 255},
+		{R: 255, G: 255, B: 255, A: 255},
+		{R: 200, G: 30, B: 30, A: 255},
+		{R: 30, G: 90, B: 200, A: 255},
+	}
+	metrics := []DistanceMetric{MetricRGB, MetricWeightedRGB, MetricCIE76, MetricCIE94, MetricCIEDE2000}
+	tests := []struct {
+		color color.RGBA
+		want  int
+	}{
+		{color.RGBA{R: 10, G: 10, B: 10, A: 255}, 0},
+		{color.RGBA{R: 240, G: 240, B: 250, A: 255}, 1},
+		{color.RGBA{R: 220, G: 40, B: 20, A: 255}, 2},
+		{color.RGBA{R: 20, G: 80, B: 220, A: 255}, 3},
+		// Every palette color is nearest to itself
+		{colors[2], 2},
+	}
+	for _, metric := range metrics {
+		matcher := newColorMatcher(colors, metric)
+		for _, tt := range tests {
+			if got := matcher.nearest(tt.color); got != tt.want {
+				t.Errorf("%s, %v: got %v, want %v", metric, tt.color, colors[got], colors[tt.want])
+			}
+		}
+	}
+}
+
+func TestRemapImage(t *testing.T) {
+	palette := testPalette()
+	img := randomImage(12, 9, 3)
+	img.SetRGBA(4, 4, color.RGBA{})
+	for _, metric := range []DistanceMetric{MetricRGB, MetricWeightedRGB, MetricCIEDE2000} {
+		out, err := RemapImage(img, palette, metric)
+		if err != nil {
+			t.Fatalf("%s: %v", metric, err)
+		}
+		matcher := newColorMatcher(palette.Colors(), metric)
+		for y := range 9 {
+			for x := range 12 {
+				got, in := out.NRGBAAt(x, y), img.RGBAAt(x, y)
+				want := color.NRGBA(palette.Entries[matcher.nearest(in)].Color)
+				if in.A == 0 {
+					want = color.NRGBA{}
+				}
+				if got != want {
+					t.Errorf("%s, pixel (%d, %d): got %v, want %v", metric, x, y, got, want)
+				}
+			}
+		}
+	}
+
+	if _, err := RemapImage(img, Palette{}, MetricRGB); !errors.Is(err, ErrTooFewColors) {
+		t.Errorf("empty palette: got %v, want ErrTooFewColors", err)
+	}
+}
+
+func TestRemapImageBounds(t *testing.T) {
+	img := randomImage(8, 8, 4).SubImage(image.Rect(2, 3, 7, 8))
+	out, err := RemapImage(img, testPalette(), MetricRGB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Bounds() != img.Bounds() {
+		t.Errorf("got bounds %v, want %v", out.Bounds(), img.Bounds())
+	}
+	if out.NRGBAAt(2, 3).A != 255 {
+		t.Errorf("the top-left pixel of the sub image was not remapped")
+	}
+}
@@ -0,0 +1,72 @@
+package pixelforging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// labelPadding is the space, in pixels, around the label of a swatch.
+const labelPadding = 4
+
+// labelFace is the bitmap font of the swatch labels.
+var labelFace font.Face = basicfont.Face7x13
+
+// labelLines returns the lines of the label of the entry at the index of the palette: its
+// position, counted from 1, its hex code and, when the palette knows the pixel counts, the
+// share of the image it covers.
+func labelLines(palette Palette, index int) []string {
+	entry := palette.Entries[index]
+	lines := []string{fmt.Sprint(index + 1), entry.Hex}
+	if palette.TotalPixels > 0 {
+		lines = append(lines, fmt.Sprintf("%.2f%%", entry.Percentage))
+	}
+	return lines
+}
+
+// labelSize returns the size of the largest label of the palette, padding included.
+func labelSize(palette Palette) (width, height int) {
+	lines := 0
+	for i := range palette.Entries {
+		labels := labelLines(palette, i)
+		lines = max(lines, len(labels))
+		for _, line := range labels {
+			width = max(width, font.MeasureString(labelFace, line).Ceil())
+		}
+	}
+	return width + 2*labelPadding, lines*labelFace.Metrics().Height.Ceil() + 2*labelPadding
+}
+
+// drawLabel writes the lines centered at the bottom of the swatch of the color c, in black
+// or white, the one of higher contrast with the color.
+func drawLabel(swatch *image.RGBA, c color.RGBA, lines []string) {
+	drawer := font.Drawer{Dst: swatch, Src: image.NewUniform(labelColor(c)), Face: labelFace}
+	metrics := labelFace.Metrics()
+	bounds := swatch.Bounds()
+	y := bounds.Max.Y - labelPadding - len(lines)*metrics.Height.Ceil() + metrics.Ascent.Ceil()
+	for _, line := range lines {
+		x := bounds.Min.X + (bounds.Dx()-drawer.MeasureString(line).Ceil())/2
+		drawer.Dot = fixed.P(x, y)
+		drawer.DrawString(line)
+		y += metrics.Height.Ceil()
+	}
+}
+
+// isLabelInk reports whether the color is one of the colors labels are written with.
+func isLabelInk(c color.RGBA) bool {
+	return c == color.RGBA{A: 255} || c == color.RGBA{R: 255, G: 255, B: 255, A: 255}
+}
+
+// labelColor returns black or white, the one of higher WCAG contrast ratio with the color.
+func labelColor(c color.RGBA) color.Color {
+	l := luminance(c)
+	// The contrast with black is (l+0.05)/0.05 and with white 1.05/(l+0.05)
+	if (l+0.05)*(l+0.05) > 0.05*1.05 {
+		return color.Black
+	}
+	return color.White
+}
@@ -0,0 +1,104 @@
+package pixelforging
+
+import (
+	"image"
+	"image/color"
+	"slices"
+	"testing"
+)
+
+func TestLabelLines(t *testing.T) {
+	counted := Palette{TotalPixels: 3, Entries: []PaletteEntry{
+		NewPaletteEntry(color.RGBA{R: 255, A: 255}, 2, 3),
+		NewPaletteEntry(color.RGBA{B: 255, A: 128}, 1, 3),
+	}}
+	// Palettes read from files have no pixel counts
+	loaded := Palette{Entries: counted.Entries}
+	tests := []struct {
+		name    string
+		palette Palette
+		index   int
+		want    []string
+	}{
+		{"counted", counted, 0, []string{"1", "#ff0000", "66.67%"}},
+		{"translucent", counted, 1, []string{"2", "#0000ff80", "33.33%"}},
+		{"loaded", loaded, 1, []string{"2", "#0000ff80"}},
+	}
+	for _, tt := range tests {
+		if got := labelLines(tt.palette, tt.index); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// The face is 7 pixels per character and 13 per line, the widest line is "#0000ff80"
+	width, height := labelSize(counted)
+	if wantWidth, wantHeight := 9*7+2*labelPadding, 3*13+2*labelPadding; width != wantWidth || height != wantHeight {
+		t.Errorf("the labels are %dx%d, want %dx%d", width, height, wantWidth, wantHeight)
+	}
+}
+
+func TestLabelColor(t *testing.T) {
+	tests := []struct {
+		color color.RGBA
+		want  color.Color
+	}{
+		{color.RGBA{A: 255}, color.White},
+		{color.RGBA{R: 255, G: 255, B: 255, A: 255}, color.Black},
+		{color.RGBA{R: 255, G: 255, A: 255}, color.Black},
+		{color.RGBA{B: 128, A: 255}, color.White},
+		{color.RGBA{R: 255, A: 255}, color.Black},
+		// The contrast with black and white is the same at a luminance of about 0.18
+		{color.RGBA{R: 117, G: 117, B: 117, A: 255}, color.White},
+		{color.RGBA{R: 119, G: 119, B: 119, A: 255}, color.Black},
+	}
+	for _, tt := range tests {
+		if got := labelColor(tt.color); got != tt.want {
+			t.Errorf("%v: got %v, want %v", tt.color, got, tt.want)
+		}
+	}
+}
+
+// inkPixels counts the pixels of the rectangle that are not of the swatch color.
+func inkPixels(img image.Image, rect image.Rectangle, swatch color.RGBA) (ink color.RGBA, n int) {
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			if c := rgbaAt(img, x, y); c != swatch {
+				ink, n = c, n+1
+			}
+		}
+	}
+	return ink, n
+}
+
+func TestRenderPaletteLabels(t *testing.T) {
+	palette := Palette{TotalPixels: 4, Entries: []PaletteEntry{
+		NewPaletteEntry(color.RGBA{R: 20, G: 20, B: 80, A: 255}, 3, 4),
+		NewPaletteEntry(color.RGBA{R: 250, G: 220, B: 90, A: 255}, 1, 4),
+		NewPaletteEntry(color.RGBA{}, 0, 4),
+	}}
+	layout := PaletteLayout{ColorsPerRow: 3, ColorWidth: 10, ColorHeight: 10, Labels: true}
+	img, err := RenderPalette(palette, layout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The swatches grow to fit the labels, that go under the 10 pixels of color
+	labelWidth, labelHeight := labelSize(palette)
+	if b := img.Bounds(); b.Dx() != 3*labelWidth || b.Dy() != 10+labelHeight {
+		t.Fatalf("got a %dx%d image, want %dx%d", b.Dx(), b.Dy(), 3*labelWidth, 10+labelHeight)
+	}
+	for i, e := range palette.Entries {
+		swatch := image.Rect(i*labelWidth, 0, (i+1)*labelWidth, 10+labelHeight)
+		ink, n := inkPixels(img, swatch, e.Color)
+		switch {
+		case e.Color.A == 0 && n != 0:
+			t.Errorf("the transparent swatch has %d label pixels", n)
+		case e.Color.A != 0 && n == 0:
+			t.Errorf("swatch %d has no label", i)
+		case e.Color.A != 0 && color.Color(ink) != labelColor(e.Color) && ink != color.RGBAModel.Convert(labelColor(e.Color)):
+			t.Errorf("swatch %d is labeled in %v, want %v", i, ink, labelColor(e.Color))
+		}
+		if _, n := inkPixels(img, image.Rect(swatch.Min.X, 0, swatch.Max.X, 10), e.Color); n != 0 {
+			t.Errorf("the label of swatch %d covers the top of the swatch", i)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package pixelforging
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// swatchArrangement is where the swatches of a palette go when drawn with a layout, shared
+// by the PNG, SVG and HTML swatch sheets.
+type swatchArrangement struct {
+	// rows are the palette indexes of the swatches of every row.
+	rows [][]int
+	// colorsPerRow is the width of the sheet, in swatches.
+	colorsPerRow int
+	colorWidth   int
+	colorHeight  int
+	// widths are the widths of the swatches of the bar layout, by palette index. The other
+	// layouts have no widths, every swatch is colorWidth wide.
+	widths []int
+	labels bool
+	// labelWidth is the width of the largest label of the palette, measured once as the
+	// sheets check it for every swatch.
+	labelWidth int
+}
+
+// arrangeSwatches places the swatches of the palette as set by the layout.
+func arrangeSwatches(palette Palette, layout PaletteLayout) (swatchArrangement, error) {
+	if err := layout.Validate(); err != nil {
+		return swatchArrangement{}, err
+	}
+	if len(palette.Entries) == 0 {
+		return swatchArrangement{}, fmt.Errorf("%w: the palette has no colors", ErrTooFewColors)
+	}
+	layout = layout.withDefaults()
+	a := swatchArrangement{
+		rows:         gridRows(len(palette.Entries), layout.ColorsPerRow),
+		colorsPerRow: layout.ColorsPerRow,
+		colorWidth:   layout.ColorWidth,
+		colorHeight:  layout.ColorHeight,
+		labels:       layout.Labels,
+	}
+	if layout.Labels {
+		labelWidth, labelHeight := labelSize(palette)
+		a.labelWidth = labelWidth
+		a.colorWidth = max(a.colorWidth, labelWidth)
+		a.colorHeight += labelHeight
+	}
+
+	switch layout.Mode {
+	case LayoutRamps:
+		ramps := palette.Ramps()
+		if len(ramps) == 0 {
+			return swatchArrangement{}, fmt.Errorf("%w: the palette has no visible colors", ErrTooFewColors)
+		}
+		a.rows, a.colorsPerRow = make([][]int, len(ramps)), 0
+		for i, ramp := range ramps {
+			a.rows[i] = ramp.Indexes
+			a.colorsPerRow = max(a.colorsPerRow, len(ramp.Indexes))
+		}
+	case LayoutBar:
+		a.widths = barWidths(palette, a.colorWidth*len(palette.Entries))
+		if slices.Max(a.widths) == 0 {
+			return swatchArrangement{}, fmt.Errorf("%w: the palette has no visible colors", ErrTooFewColors)
+		}
+		a.rows, a.colorsPerRow = [][]int{{}}, 0
+		for i, width := range a.widths {
+			if width > 0 {
+				a.rows[0] = append(a.rows[0], i)
+			}
+		}
+	}
+	if _, size := a.cells(); size.X*size.Y > maxSheetArea {
+		return swatchArrangement{}, fmt.Errorf("%w: the palette image would be %dx%d pixels, more than the %d pixels allowed", ErrInvalidDimensions, size.X, size.Y, maxSheetArea)
+	}
+	return a, nil
+}
+
+// swatchCell is the rectangle of the swatch of a palette entry.
+type swatchCell struct {
+	index int
+	rect  image.Rectangle
+}
+
+// cells returns the rectangles of the swatches, row by row, and the size of the sheet.
+func (a swatchArrangement) cells() ([]swatchCell, image.Point) {
+	var cells []swatchCell
+	size := image.Pt(a.colorsPerRow*a.colorWidth, len(a.rows)*a.colorHeight)
+	for y, row := range a.rows {
+		x := 0
+		for _, index := range row {
+			width := a.colorWidth
+			if a.widths != nil {
+				width = a.widths[index]
+			}
+			cells = append(cells, swatchCell{index: index, rect: image.Rect(x, y*a.colorHeight, x+width, (y+1)*a.colorHeight)})
+			x += width
+		}
+		size.X = max(size.X, x)
+	}
+	return cells, size
+}
+
+// hasLabel reports whether the swatch of the cell is drawn with its label: the labels are
+// set, the color is not fully transparent and the swatch is wide enough for the label.
+func (a swatchArrangement) hasLabel(palette Palette, cell swatchCell) bool {
+	if !a.labels || palette.Entries[cell.index].Color.A == 0 {
+		return false
+	}
+	return cell.rect.Dx() >= a.labelWidth
+}
+
+// IsSwatchSheet reports whether the format draws the swatches of the palette, arranged by a
+// PaletteLayout, instead of listing its colors.
+func (f PaletteFormat) IsSwatchSheet() bool {
+	return f == PaletteFormatPNG || f == PaletteFormatSVG || f == PaletteFormatHTML
+}
+
+// EncodeSwatchSheet writes the swatches of the palette, arranged by the layout, to w in a
+// swatch sheet format: a PNG image, an SVG with a <rect> per color, or an HTML page that
+// copies the hex code of a color when it is clicked. The name is the title of the SVG and
+// HTML sheets.
+func EncodeSwatchSheet(w io.Writer, palette Palette, format PaletteFormat, layout PaletteLayout, name string) error {
+	if format == PaletteFormatPNG {
+		img, err := RenderPalette(palette, layout)
+		if err != nil {
+			return err
+		}
+		return png.Encode(w, img)
+	}
+	if format != PaletteFormatSVG && format != PaletteFormatHTML {
+		return fmt.Errorf("%w: palette format %q is not a swatch sheet", ErrUnsupportedFormat, format)
+	}
+
+	arrangement, err := arrangeSwatches(palette, layout)
+	if err != nil {
+		return err
+	}
+	buf := bufio.NewWriter(w)
+	if format == PaletteFormatSVG {
+		encodeSVG(buf, palette, arrangement, name)
+	} else {
+		encodeHTML(buf, palette, arrangement, name)
+	}
+	return buf.Flush()
+}
+
+// SaveSwatchSheet saves the swatch sheet of the palette on the output file path.
+func SaveSwatchSheet(palette Palette, outPutFilePath string, format PaletteFormat, layout PaletteLayout, name string) (err error) {
+	file, err := os.Create(outPutFilePath)
+	if err != nil {
+		return err
+	}
+	defer func(file *os.File) {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}(file)
+
+	return EncodeSwatchSheet(file, palette, format, layout, name)
+}
+
+// svgFill returns the fill attributes of the color, the opacity being set only for the
+// colors that are not fully opaque.
+func svgFill(e PaletteEntry) string {
+	fill := fmt.Sprintf(`fill="#%02x%02x%02x"`, e.Color.R, e.Color.G, e.Color.B)
+	if e.Color.A < 255 {
+		fill += fmt.Sprintf(` fill-opacity="%.3f"`, float64(e.Color.A)/255)
+	}
+	return fill
+}
+
+// textColor returns the hex code of the label color of the swatch, see labelColor.
+func textColor(e PaletteEntry) string {
+	if labelColor(e.Color) == color.Black {
+		return "#000000"
+	}
+	return "#ffffff"
+}
+
+// encodeSVG writes the sheet as an SVG image. Every color is a <rect> with its hex code as
+// the <title> shown by the viewers when the pointer is over it.
+func encodeSVG(w *bufio.Writer, palette Palette, a swatchArrangement, name string) {
+	cells, size := a.cells()
+	fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", size.X, size.Y, size.X, size.Y)
+	fmt.Fprintf(w, "  <title>%s</title>\n", html.EscapeString(name))
+	lineHeight := labelFace.Metrics().Height.Ceil()
+	for _, cell := range cells {
+		e := palette.Entries[cell.index]
+		if e.Color.A == 0 {
+			continue
+		}
+		r := cell.rect
+		fmt.Fprintf(w, `  <rect x="%d" y="%d" width="%d" height="%d" %s><title>%s</title></rect>`+"\n", r.Min.X, r.Min.Y, r.Dx(), r.Dy(), svgFill(e), e.Hex)
+		if !a.hasLabel(palette, cell) {
+			continue
+		}
+		lines := labelLines(palette, cell.index)
+		y := r.Max.Y - labelPadding - len(lines)*lineHeight + lineHeight/2
+		fmt.Fprintf(w, `  <text x="%d" fill="%s" font-family="monospace" font-size="11" text-anchor="middle" dominant-baseline="central">`, r.Min.X+r.Dx()/2, textColor(e))
+		for i, line := range lines {
+			fmt.Fprintf(w, `<tspan x="%d" y="%d">%s</tspan>`, r.Min.X+r.Dx()/2, y+i*lineHeight, line)
+		}
+		fmt.Fprintln(w, "</text>")
+	}
+	fmt.Fprintln(w, "</svg>")
+}
+
+// htmlScript copies the hex code of the clicked swatch and shows it in the status line.
+const htmlScript = `document.querySelectorAll(".swatch").forEach(function (swatch) {
+  swatch.addEventListener("click", function () {
+    var hex = swatch.dataset.hex;
+    var status = document.getElementById("status");
+    navigator.clipboard.writeText(hex).then(function () {
+      status.textContent = hex + " copied to the clipboard";
+    }, function () {
+      status.textContent = "Could not copy " + hex;
+    });
+  });
+});`
+
+// encodeHTML writes the sheet as a self-contained HTML page, without external files. Every
+// color is a button placed like in the PNG sheet that copies its hex code when clicked.
+func encodeHTML(w *bufio.Writer, palette Palette, a swatchArrangement, name string) {
+	cells, size := a.cells()
+	title := html.EscapeString(name)
+	fmt.Fprintln(w, "<!DOCTYPE html>")
+	fmt.Fprintln(w, `<html lang="en">`)
+	fmt.Fprintln(w, "<head>")
+	fmt.Fprintln(w, `<meta charset="utf-8">`)
+	fmt.Fprintf(w, "<title>%s</title>\n", title)
+	fmt.Fprintln(w, "<style>")
+	fmt.Fprintln(w, "body { font-family: monospace; margin: 16px; }")
+	fmt.Fprintf(w, ".sheet { position: relative; width: %dpx; height: %dpx; }\n", size.X, size.Y)
+	fmt.Fprintln(w, ".swatch { position: absolute; box-sizing: border-box; margin: 0; padding: 0 0 4px; border: 0; cursor: pointer;")
+	fmt.Fprintln(w, "  display: flex; flex-direction: column; justify-content: flex-end; align-items: center; font: 11px/13px monospace; }")
+	fmt.Fprintln(w, ".swatch:hover { outline: 2px solid #888; z-index: 1; }")
+	fmt.Fprintln(w, "</style>")
+	fmt.Fprintln(w, "</head>")
+	fmt.Fprintln(w, "<body>")
+	fmt.Fprintf(w, "<h1>%s</h1>\n", title)
+	fmt.Fprintln(w, `<p id="status">Click a color to copy its hex code.</p>`)
+	fmt.Fprintln(w, `<div class="sheet">`)
+	for _, cell := range cells {
+		e := palette.Entries[cell.index]
+		if e.Color.A == 0 {
+			continue
+		}
+		r := cell.rect
+		fmt.Fprintf(w, `<button class="swatch" data-hex="%s" title="%s" style="left: %dpx; top: %dpx; width: %dpx; height: %dpx; background: rgba(%d, %d, %d, %.3f); color: %s">`,
+			e.Hex, e.Hex, r.Min.X, r.Min.Y, r.Dx(), r.Dy(), e.Color.R, e.Color.G, e.Color.B, float64(e.Color.A)/255, textColor(e))
+		if a.hasLabel(palette, cell) {
+			fmt.Fprint(w, strings.Join(labelLines(palette, cell.index), "<br>"))
+		}
+		fmt.Fprintln(w, "</button>")
+	}
+	fmt.Fprintln(w, "</div>")
+	fmt.Fprintf(w, "<script>\n%s\n</script>\n", htmlScript)
+	fmt.Fprintln(w, "</body>")
+	fmt.Fprintln(w, "</html>")
+}
@@ -0,0 +1,130 @@
+package pixelforging
+
+import (
+	"bytes"
+	"errors"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// sequentialPalette returns a palette of n opaque colors, all different, that cover one
+// pixel each.
+func sequentialPalette(n int) Palette {
+	palette := Palette{TotalPixels: n}
+	for i := range n {
+		c := color.RGBA{R: uint8(i >> 16), G: uint8(i >> 8), B: uint8(i), A: 255}
+		palette.Entries = append(palette.Entries, NewPaletteEntry(c, 1, n))
+	}
+	return palette
+}
+
+func TestEncodeSwatchSheetLabels(t *testing.T) {
+	palette := sequentialPalette(8000)
+	palette.Entries[1] = NewPaletteEntry(color.RGBA{}, 0, palette.TotalPixels)
+	layout := PaletteLayout{ColorsPerRow: 100, ColorWidth: 10, ColorHeight: 10, Labels: true}
+	tests := []struct {
+		format PaletteFormat
+		swatch string
+		label  string
+	}{
+		{PaletteFormatSVG, "<rect ", "<text "},
+		{PaletteFormatHTML, `<button class="swatch"`, "<br>"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := EncodeSwatchSheet(&buf, palette, tt.format, layout, "Test"); err != nil {
+				t.Fatal(err)
+			}
+			// The transparent entry has neither a swatch nor a label
+			want := len(palette.Entries) - 1
+			if got := strings.Count(buf.String(), tt.swatch); got != want {
+				t.Errorf("got %d swatches, want %d", got, want)
+			}
+			if got := strings.Count(buf.String(), tt.label); got < want {
+				t.Errorf("got %d labels, want at least %d", got, want)
+			}
+		})
+	}
+}
+
+func TestEncodeSVG(t *testing.T) {
+	palette := Palette{Entries: []PaletteEntry{
+		NewPaletteEntry(color.RGBA{R: 190, G: 38, B: 51, A: 255}, 0, 0),
+		NewPaletteEntry(color.RGBA{}, 0, 0),
+		NewPaletteEntry(color.RGBA{R: 1, G: 2, B: 3, A: 51}, 0, 0),
+	}}
+	var buf bytes.Buffer
+	layout := PaletteLayout{ColorsPerRow: 2, ColorWidth: 5, ColorHeight: 4}
+	if err := EncodeSwatchSheet(&buf, palette, PaletteFormatSVG, layout, "Rock & Roll"); err != nil {
+		t.Fatal(err)
+	}
+	want := `<svg xmlns="http://www.w3.org/2000/svg" width="10" height="8" viewBox="0 0 10 8">
+  <title>Rock &amp; Roll</title>
+  <rect x="0" y="0" width="5" height="4" fill="#be2633"><title>#be2633</title></rect>
+  <rect x="0" y="4" width="5" height="4" fill="#010203" fill-opacity="0.200"><title>#01020333</title></rect>
+</svg>
+`
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestEncodeHTML(t *testing.T) {
+	palette := Palette{Entries: []PaletteEntry{
+		NewPaletteEntry(color.RGBA{R: 250, G: 240, B: 200, A: 255}, 0, 0),
+		NewPaletteEntry(color.RGBA{R: 10, G: 20, B: 30, A: 128}, 0, 0),
+	}}
+	var buf bytes.Buffer
+	layout := PaletteLayout{ColorsPerRow: 1, ColorWidth: 80, ColorHeight: 20, Labels: true}
+	if err := EncodeSwatchSheet(&buf, palette, PaletteFormatHTML, layout, "<Sprite>"); err != nil {
+		t.Fatal(err)
+	}
+	page := buf.String()
+	for _, want := range []string{
+		"<title>&lt;Sprite&gt;</title>",
+		`data-hex="#faf0c8"`,
+		"background: rgba(250, 240, 200, 1.000); color: #000000\">1<br>#faf0c8</button>",
+		"background: rgba(10, 20, 30, 0.502); color: #ffffff\">2<br>#0a141e80</button>",
+		"navigator.clipboard.writeText(hex)",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("the page does not contain %q", want)
+		}
+	}
+}
+
+func TestEncodeSwatchSheetFormats(t *testing.T) {
+	palette := testPalette()
+	var buf bytes.Buffer
+	if err := EncodeSwatchSheet(&buf, palette, PaletteFormatPNG, PaletteLayout{}, "Test"); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendered, err := RenderPalette(palette, PaletteLayout{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds() != rendered.Bounds() {
+		t.Errorf("the PNG sheet is %v, want %v", img.Bounds(), rendered.Bounds())
+	}
+
+	for _, format := range []PaletteFormat{PaletteFormatGPL, PaletteFormatASE, "pdf"} {
+		if format.IsSwatchSheet() {
+			t.Errorf("%s is a swatch sheet", format)
+		}
+		if err := EncodeSwatchSheet(&bytes.Buffer{}, palette, format, PaletteLayout{}, "Test"); !errors.Is(err, ErrUnsupportedFormat) {
+			t.Errorf("%s: got %v, want ErrUnsupportedFormat", format, err)
+		}
+	}
+	for _, format := range []PaletteFormat{PaletteFormatPNG, PaletteFormatSVG, PaletteFormatHTML} {
+		if !format.IsSwatchSheet() {
+			t.Errorf("%s is not a swatch sheet", format)
+		}
+	}
+}
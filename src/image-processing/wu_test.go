@@ -0,0 +1,49 @@
+package pixelforging
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestWuColorCount(t *testing.T) {
+	histogram := newTestHistogram(t, spreadColors()...)
+	for _, colorNum := range []int{1, 2, 6, 16, 64} {
+		palette, err := WuQuantizer{}.Quantize(histogram, colorNum)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(palette) != colorNum {
+			t.Errorf("%d colors: got %d colors", colorNum, len(palette))
+		}
+		total := 0
+		for _, c := range palette {
+			total += c.Count
+		}
+		if total != histogram.Total() {
+			t.Errorf("%d colors: the boxes hold %d pixels, want %d", colorNum, total, histogram.Total())
+		}
+	}
+}
+
+func TestWuClusters(t *testing.T) {
+	histogram := newTestHistogram(t, clusteredColors()...)
+	palette, err := WuQuantizer{}.Quantize(histogram, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every cluster is a box, sorted by pixel count: the reds, the greens and the blues
+	want := []ColorCount{
+		{color.RGBA{R: 234, G: 6, B: 6, A: 255}, 20},
+		{color.RGBA{R: 6, G: 194, B: 6, A: 255}, 15},
+		{color.RGBA{R: 6, G: 6, B: 214, A: 255}, 10},
+	}
+	if len(palette) != len(want) {
+		t.Fatalf("got %v, want %v", palette, want)
+	}
+	for i := range want {
+		if palette[i] != want[i] {
+			t.Errorf("got %v, want %v", palette, want)
+			break
+		}
+	}
+}